@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/bot"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"time"
+)
+
+func main() {
+	variantName := flag.String("variant", engine.ClassicName, "game variant")
+	size := flag.Int("size", 15, "board size")
+	winLength := flag.Int("k", 5, "number in a row needed to win")
+	thinkTime := flag.Duration("time", 2*time.Second, "think time budget")
+	iterations := flag.Int("iterations", 0, "iteration cap (0 means no cap)")
+	verbose := flag.Bool("v", false, "print search statistics")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [moves...]\n\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "Replays the given moves (e.g. H8 H9 G7) and prints the move MCTS picks.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	variant, ok := engine.LookupVariant(*variantName)
+	if !ok {
+		log.Fatalf("unknown variant %q", *variantName)
+	}
+	state, err := variant.NewState(*size, *winLength)
+	if err != nil {
+		log.Fatalf("invalid board: %v", err)
+	}
+
+	for _, arg := range flag.Args() {
+		for _, input := range strings.Split(arg, ",") {
+			if input = strings.TrimSpace(input); input == "" {
+				continue
+			}
+			move, err := state.ParseMove(input)
+			if err == nil {
+				err = state.Apply(move)
+			}
+			if err != nil {
+				log.Fatalf("move %s: %v", input, err)
+			}
+		}
+	}
+
+	if state.Outcome().Over() {
+		log.Fatalf("the game is already over")
+	}
+
+	opts := bot.Options{ThinkTime: *thinkTime, MaxIterations: *iterations}
+	if err := opts.Validate(); err != nil {
+		log.Fatalf("invalid search budget: %v", err)
+	}
+
+	searcher := &bot.MCTS{ThinkTime: opts.ThinkTime, MaxIterations: opts.MaxIterations}
+	result := searcher.Search(state)
+	fmt.Println(state.FormatMove(result.Move))
+	if *verbose {
+		fmt.Printf("iterations: %d, visits: %d, win rate: %.1f%%\n", result.Iterations, result.Visits, result.WinRate*100)
+	}
+}
@@ -12,7 +12,7 @@ func main() {
 	dB := config.InitDB(cfg)
 	defer dB.Close()
 
-	s := handlers.NewServer("0.0.0.0:23", dB)
+	s := handlers.NewServer("0.0.0.0:23", dB, cfg)
 	fmt.Printf("starting server on %s\r\n", s.ListenAddr)
 	if err := handlers.ListenAndPair(s); err != nil {
 		log.Printf("server failed: %v", err)
@@ -5,18 +5,44 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/bot"
 	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
 func LoadConfig() *models.Config {
-	return &models.Config{
+	cfg := &models.Config{
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", ""),
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBName:     getEnv("DB_NAME", ""),
+
+		BotFillTimeout: getEnvDuration("BOT_FILL_TIMEOUT", "60s"),
+		BotFillLevel:   getEnv("BOT_FILL_LEVEL", "heuristic"),
+		BotThinkTime:   getEnvDuration("BOT_THINK_TIME", "2s"),
+		BotIterations:  getEnvInt("BOT_ITERATIONS", "200000"),
+		ReconnectGrace: getEnvDuration("RECONNECT_GRACE", "60s"),
+		SessionPolicy:  getEnv("SESSION_POLICY", "reject"),
+		RatingMinGames: getEnvInt("RATING_MIN_GAMES", "5"),
+
+		Admins:            getEnvList("ADMINS"),
+		TournamentCheckIn: getEnvDuration("TOURNAMENT_CHECK_IN", "5m"),
+
+		HTTPAddr: getEnv("HTTP_ADDR", "0.0.0.0:8080"),
+	}
+	validateBotOptions(cfg)
+	return cfg
+}
+
+func validateBotOptions(cfg *models.Config) {
+	opts := bot.Options{ThinkTime: cfg.BotThinkTime, MaxIterations: cfg.BotIterations}
+	if err := opts.Validate(); err != nil {
+		log.Fatalf("Invalid bot settings (BOT_THINK_TIME, BOT_ITERATIONS): %v", err)
 	}
 }
 
@@ -31,6 +57,34 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+func getEnvDuration(key, defaultValue string) time.Duration {
+	value := getEnv(key, defaultValue)
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Environment variable %s is not a valid duration: %v", key, err)
+	}
+	return d
+}
+
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvInt(key, defaultValue string) int {
+	value := getEnv(key, defaultValue)
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Environment variable %s is not a valid number: %v", key, err)
+	}
+	return n
+}
+
 func GetDBConnectionString(c *models.Config) string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName)
@@ -47,5 +101,9 @@ func InitDB(cfg *models.Config) *sql.DB {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
+	if err := Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
 	return db
 }
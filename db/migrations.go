@@ -0,0 +1,60 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS players (
+		nickname  TEXT PRIMARY KEY,
+		password  TEXT NOT NULL,
+		wins      INTEGER NOT NULL DEFAULT 0,
+		losses    INTEGER NOT NULL DEFAULT 0,
+		draws     INTEGER NOT NULL DEFAULT 0,
+		all_games INTEGER NOT NULL DEFAULT 0
+	)`,
+	`ALTER TABLE players ADD COLUMN IF NOT EXISTS timeout_losses INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE players ADD COLUMN IF NOT EXISTS resignations INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE players ADD COLUMN IF NOT EXISTS rating DOUBLE PRECISION NOT NULL DEFAULT 1500`,
+	`ALTER TABLE players ADD COLUMN IF NOT EXISTS rating_deviation DOUBLE PRECISION NOT NULL DEFAULT 350`,
+	`ALTER TABLE players ADD COLUMN IF NOT EXISTS rating_volatility DOUBLE PRECISION NOT NULL DEFAULT 0.06`,
+	`ALTER TABLE players ADD COLUMN IF NOT EXISTS rated_games INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS games (
+		id             TEXT PRIMARY KEY,
+		player1        TEXT NOT NULL,
+		player2        TEXT NOT NULL,
+		symbol1        TEXT NOT NULL,
+		symbol2        TEXT NOT NULL,
+		variant        TEXT NOT NULL,
+		board_size     INTEGER NOT NULL,
+		win_length     INTEGER NOT NULL,
+		time_base      INTEGER NOT NULL DEFAULT 0,
+		time_increment INTEGER NOT NULL DEFAULT 0,
+		rated          BOOLEAN NOT NULL DEFAULT FALSE,
+		against_bot    BOOLEAN NOT NULL DEFAULT FALSE,
+		started_at     TIMESTAMPTZ NOT NULL,
+		ended_at       TIMESTAMPTZ NOT NULL,
+		winner         TEXT,
+		end_reason     TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS games_player1_idx ON games (player1, ended_at)`,
+	`CREATE INDEX IF NOT EXISTS games_player2_idx ON games (player2, ended_at)`,
+	`CREATE TABLE IF NOT EXISTS moves (
+		game_id   TEXT NOT NULL REFERENCES games (id) ON DELETE CASCADE,
+		ply       INTEGER NOT NULL,
+		side      INTEGER NOT NULL,
+		move      TEXT NOT NULL,
+		played_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (game_id, ply)
+	)`,
+}
+
+func Migrate(db *sql.DB) error {
+	for i, statement := range migrations {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("migration %d failed: %w", i+1, err)
+		}
+	}
+	return nil
+}
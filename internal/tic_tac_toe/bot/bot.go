@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"time"
+)
+
+const (
+	RandomLevel    = "random"
+	HeuristicLevel = "heuristic"
+	PerfectLevel   = "perfect"
+	MCTSLevel      = "mcts"
+)
+
+type Options struct {
+	ThinkTime     time.Duration
+	MaxIterations int
+}
+
+// Validate rejects search budgets that would stop a search before it
+// looked at a single move. A MaxIterations of 0 means no cap.
+func (o Options) Validate() error {
+	if o.ThinkTime <= 0 {
+		return fmt.Errorf("think time must be positive, got %s", o.ThinkTime)
+	}
+	if o.MaxIterations < 0 {
+		return fmt.Errorf("iteration cap must not be negative, got %d", o.MaxIterations)
+	}
+	return nil
+}
+
+type Player interface {
+	Name() string
+	ChooseMove(state engine.State) engine.Move
+}
+
+// Budgeted is implemented by players that search for a while before they
+// move. ChooseMoveWithin never thinks longer than budget, so a bot playing
+// on a clock can be kept from losing on time.
+type Budgeted interface {
+	ChooseMoveWithin(state engine.State, budget time.Duration) engine.Move
+}
+
+// capThinkTime returns the shorter of thinkTime (0 meaning the default)
+// and budget.
+func capThinkTime(thinkTime, budget time.Duration) time.Duration {
+	if thinkTime == 0 {
+		thinkTime = defaultThinkTime
+	}
+	return min(thinkTime, budget)
+}
+
+func Levels() []string {
+	return []string{RandomLevel, HeuristicLevel, PerfectLevel, MCTSLevel}
+}
+
+func New(level string, opts Options) (Player, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case RandomLevel:
+		return &Random{}, nil
+	case HeuristicLevel:
+		return &Heuristic{}, nil
+	case PerfectLevel:
+		return &Minimax{ThinkTime: opts.ThinkTime}, nil
+	case MCTSLevel:
+		return &MCTS{ThinkTime: opts.ThinkTime, MaxIterations: opts.MaxIterations}, nil
+	}
+	return nil, fmt.Errorf("unknown bot level '%s'", level)
+}
+
+type Random struct{}
+
+func (*Random) Name() string {
+	return RandomLevel
+}
+
+func (*Random) ChooseMove(state engine.State) engine.Move {
+	moves := state.LegalMoves()
+	return moves[rand.Intn(len(moves))]
+}
+
+func winsFor(side engine.Side) engine.Outcome {
+	if side == engine.First {
+		return engine.FirstWins
+	}
+	return engine.SecondWins
+}
+
+func after(state engine.State, m engine.Move) engine.State {
+	next := state.Clone()
+	if err := next.Apply(m); err != nil {
+		panic(fmt.Sprintf("bot: legal move %v rejected: %v", m, err))
+	}
+	return next
+}
@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"math/rand"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+)
+
+// Heuristic takes an immediate win when there is one, avoids moves that
+// hand the opponent an immediate win, and otherwise prefers central cells
+// next to its own stones.
+type Heuristic struct{}
+
+func (*Heuristic) Name() string {
+	return HeuristicLevel
+}
+
+func (*Heuristic) ChooseMove(state engine.State) engine.Move {
+	me := state.Turn()
+	candidates := candidateMoves(state)
+
+	for _, m := range candidates {
+		if after(state, m).Outcome() == winsFor(me) {
+			return m
+		}
+	}
+
+	var safe []engine.Move
+	for _, m := range candidates {
+		if !opponentWinsNext(after(state, m), me) {
+			safe = append(safe, m)
+		}
+	}
+	if len(safe) == 0 {
+		safe = candidates
+	}
+
+	best := safe[0]
+	bestScore := -1.0
+	for _, m := range safe {
+		score := positionalScore(state, m) + rand.Float64()
+		if score > bestScore {
+			best, bestScore = m, score
+		}
+	}
+	return best
+}
+
+func opponentWinsNext(state engine.State, me engine.Side) bool {
+	if state.Outcome().Over() {
+		return state.Outcome() == winsFor(me.Other())
+	}
+	for _, reply := range candidateMoves(state) {
+		if after(state, reply).Outcome() == winsFor(me.Other()) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidateMoves trims the legal moves on large boards to the cells next to
+// stones already played, which is where every line gets started or blocked.
+func candidateMoves(state engine.State) []engine.Move {
+	moves := state.LegalMoves()
+	pos, ok := state.(*engine.Position)
+	if !ok || pos.Size <= 5 || pos.EmptyCells() == len(pos.Cells) {
+		return moves
+	}
+
+	var near []engine.Move
+	for _, m := range moves {
+		if hasNeighbour(pos, m.Row, m.Col, 2) {
+			near = append(near, m)
+		}
+	}
+	if len(near) == 0 {
+		return moves
+	}
+	return near
+}
+
+func hasNeighbour(pos *engine.Position, row, col, radius int) bool {
+	for dr := -radius; dr <= radius; dr++ {
+		for dc := -radius; dc <= radius; dc++ {
+			r, c := row+dr, col+dc
+			if r < 0 || r >= pos.Size || c < 0 || c >= pos.Size {
+				continue
+			}
+			if pos.At(r, c) != engine.Empty {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func positionalScore(state engine.State, m engine.Move) float64 {
+	size := 9
+	if pos, ok := state.(*engine.Position); ok {
+		size = pos.Size
+	}
+
+	centre := float64(size-1) / 2
+	dr, dc := float64(m.Row)-centre, float64(m.Col)-centre
+	score := float64(size) - (abs(dr) + abs(dc))
+
+	if pos, ok := state.(*engine.Position); ok {
+		for r := m.Row - 1; r <= m.Row+1; r++ {
+			for c := m.Col - 1; c <= m.Col+1; c++ {
+				if r >= 0 && r < pos.Size && c >= 0 && c < pos.Size && pos.At(r, c) != engine.Empty {
+					score += 2
+				}
+			}
+		}
+	}
+	return score
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
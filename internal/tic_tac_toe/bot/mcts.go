@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"math"
+	"math/rand"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"time"
+)
+
+const defaultExploration = 1.4
+
+// MCTS is a Monte Carlo Tree Search player. It only ever works on clones
+// of the state it is given and holds no shared locks, so a long search
+// never stalls anything but the game that asked for the move.
+type MCTS struct {
+	ThinkTime     time.Duration
+	MaxIterations int
+	Exploration   float64
+}
+
+type SearchResult struct {
+	Move       engine.Move
+	Iterations int
+	Visits     int
+	WinRate    float64
+}
+
+type mctsNode struct {
+	parent   *mctsNode
+	move     engine.Move
+	mover    engine.Side
+	children []*mctsNode
+	untried  []engine.Move
+	visits   float64
+	wins     float64
+}
+
+func (*MCTS) Name() string {
+	return MCTSLevel
+}
+
+func (b *MCTS) ChooseMove(state engine.State) engine.Move {
+	return b.Search(state).Move
+}
+
+func (b *MCTS) ChooseMoveWithin(state engine.State, budget time.Duration) engine.Move {
+	capped := *b
+	capped.ThinkTime = capThinkTime(b.ThinkTime, budget)
+	return capped.Search(state).Move
+}
+
+func (b *MCTS) Search(state engine.State) SearchResult {
+	thinkTime := b.ThinkTime
+	if thinkTime == 0 {
+		thinkTime = defaultThinkTime
+	}
+	exploration := b.Exploration
+	if exploration == 0 {
+		exploration = defaultExploration
+	}
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	root := &mctsNode{mover: state.Turn().Other(), untried: rootMoves(state)}
+	if len(root.untried) == 1 {
+		return SearchResult{Move: root.untried[0]}
+	}
+	for _, m := range root.untried {
+		if after(state, m).Outcome() == winsFor(state.Turn()) {
+			return SearchResult{Move: m}
+		}
+	}
+
+	deadline := time.Now().Add(thinkTime)
+	iterations := 0
+	for ; b.MaxIterations == 0 || iterations < b.MaxIterations; iterations++ {
+		if iterations%64 == 0 && time.Now().After(deadline) {
+			break
+		}
+
+		n := root
+		st := state.Clone()
+
+		for len(n.untried) == 0 && len(n.children) > 0 {
+			n = n.selectChild(exploration)
+			st.Apply(n.move)
+		}
+
+		if len(n.untried) > 0 {
+			i := rng.Intn(len(n.untried))
+			m := n.untried[i]
+			n.untried[i] = n.untried[len(n.untried)-1]
+			n.untried = n.untried[:len(n.untried)-1]
+
+			mover := st.Turn()
+			st.Apply(m)
+			child := &mctsNode{parent: n, move: m, mover: mover, untried: candidateMoves(st)}
+			n.children = append(n.children, child)
+			n = child
+		}
+
+		outcome := rollout(st, rng)
+		winner, decided := outcome.Winner()
+		for ; n != nil; n = n.parent {
+			n.visits++
+			if !decided {
+				n.wins += 0.5
+			} else if winner == n.mover {
+				n.wins++
+			}
+		}
+	}
+
+	if len(root.children) == 0 {
+		return SearchResult{Move: root.untried[0], Iterations: iterations}
+	}
+	best := root.children[0]
+	for _, child := range root.children[1:] {
+		if child.visits > best.visits {
+			best = child
+		}
+	}
+	return SearchResult{
+		Move:       best.move,
+		Iterations: iterations,
+		Visits:     int(best.visits),
+		WinRate:    best.wins / math.Max(best.visits, 1),
+	}
+}
+
+// rootMoves drops the moves that give the opponent an immediate win, as
+// long as any other move is left.
+func rootMoves(state engine.State) []engine.Move {
+	moves := candidateMoves(state)
+	var safe []engine.Move
+	for _, m := range moves {
+		if !opponentWinsNext(after(state, m), state.Turn()) {
+			safe = append(safe, m)
+		}
+	}
+	if len(safe) == 0 {
+		return moves
+	}
+	return safe
+}
+
+func (n *mctsNode) selectChild(exploration float64) *mctsNode {
+	var best *mctsNode
+	bestScore := math.Inf(-1)
+	logVisits := math.Log(n.visits)
+	for _, child := range n.children {
+		score := child.wins/child.visits + exploration*math.Sqrt(logVisits/child.visits)
+		if score > bestScore {
+			best, bestScore = child, score
+		}
+	}
+	return best
+}
+
+func rollout(state engine.State, rng *rand.Rand) engine.Outcome {
+	if pos, ok := state.(*engine.Position); ok {
+		return rolloutGrid(pos, rng)
+	}
+	for {
+		if outcome := state.Outcome(); outcome.Over() {
+			return outcome
+		}
+		moves := state.LegalMoves()
+		state.Apply(moves[rng.Intn(len(moves))])
+	}
+}
+
+// rolloutGrid plays the empty cells of a grid position in a random order,
+// which avoids rebuilding the legal move list after every move.
+func rolloutGrid(pos *engine.Position, rng *rand.Rand) engine.Outcome {
+	empty := make([]int, 0, pos.EmptyCells())
+	for i, cell := range pos.Cells {
+		if cell == engine.Empty {
+			empty = append(empty, i)
+		}
+	}
+	rng.Shuffle(len(empty), func(i, j int) { empty[i], empty[j] = empty[j], empty[i] })
+
+	for _, cell := range empty {
+		if outcome := pos.Outcome(); outcome.Over() {
+			return outcome
+		}
+		symbols := pos.Variant().Symbols(pos.Turn())
+		pos.Apply(engine.Move{
+			Row:    cell / pos.Size,
+			Col:    cell % pos.Size,
+			Symbol: symbols[rng.Intn(len(symbols))],
+		})
+	}
+	return pos.Outcome()
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"testing"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"time"
+)
+
+func TestMCTSSearchWithoutIterations(t *testing.T) {
+	tests := []struct {
+		name   string
+		search MCTS
+	}{
+		{name: "negative iteration cap", search: MCTS{ThinkTime: time.Second, MaxIterations: -1}},
+		{name: "tiny think time", search: MCTS{ThinkTime: time.Nanosecond}},
+		{name: "negative think time", search: MCTS{ThinkTime: -time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := engine.NewPosition(15, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := tt.search.Search(state)
+			if err := state.Validate(result.Move); err != nil {
+				t.Errorf("Search returned an illegal move %s: %v", state.FormatMove(result.Move), err)
+			}
+		})
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts  Options
+		valid bool
+	}{
+		{opts: Options{ThinkTime: 2 * time.Second, MaxIterations: 200000}, valid: true},
+		{opts: Options{ThinkTime: time.Second}, valid: true},
+		{opts: Options{ThinkTime: 0}, valid: false},
+		{opts: Options{ThinkTime: -time.Second}, valid: false},
+		{opts: Options{ThinkTime: time.Second, MaxIterations: -1}, valid: false},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v.Validate() = %v, want valid %v", tt.opts, err, tt.valid)
+		}
+	}
+}
+
+func TestChooseMoveWithinBudget(t *testing.T) {
+	players := []Budgeted{
+		&MCTS{ThinkTime: 5 * time.Second},
+		&Minimax{ThinkTime: 5 * time.Second},
+	}
+	for _, p := range players {
+		state, err := engine.NewPosition(15, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		move := p.ChooseMoveWithin(state, 100*time.Millisecond)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%T thought for %s with a 100ms budget", p, elapsed)
+		}
+		if err := state.Validate(move); err != nil {
+			t.Errorf("%T chose an illegal move: %v", p, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"math/rand"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"time"
+)
+
+const (
+	winScore         = 1000
+	infinity         = 10 * winScore
+	defaultThinkTime = 2 * time.Second
+)
+
+// Minimax searches with alpha-beta pruning, deepening one ply at a time
+// until the game tree is exhausted or the think time runs out. On small
+// boards the whole tree fits and it plays perfectly; on larger ones it
+// plays the best move found at the deepest completed depth.
+type Minimax struct {
+	MaxDepth  int
+	ThinkTime time.Duration
+}
+
+type search struct {
+	deadline time.Time
+	nodes    int
+	timedOut bool
+	// cutoff is set when a non-terminal position was scored as even
+	// because the depth ran out, meaning a deeper search may change things.
+	cutoff bool
+}
+
+func (b *Minimax) ChooseMoveWithin(state engine.State, budget time.Duration) engine.Move {
+	capped := *b
+	capped.ThinkTime = capThinkTime(b.ThinkTime, budget)
+	return capped.ChooseMove(state)
+}
+
+func (*Minimax) Name() string {
+	return PerfectLevel
+}
+
+func (b *Minimax) ChooseMove(state engine.State) engine.Move {
+	moves := candidateMoves(state)
+	rand.Shuffle(len(moves), func(i, j int) { moves[i], moves[j] = moves[j], moves[i] })
+
+	thinkTime := b.ThinkTime
+	if thinkTime == 0 {
+		thinkTime = defaultThinkTime
+	}
+	maxDepth := b.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = len(state.LegalMoves())
+	}
+
+	srch := &search{deadline: time.Now().Add(thinkTime)}
+	best := moves[0]
+	for depth := 1; depth <= maxDepth; depth++ {
+		srch.cutoff = false
+		move, score, ok := srch.root(state, moves, depth)
+		if !ok {
+			break
+		}
+		best = move
+		if !srch.cutoff || score >= winScore-depth {
+			break
+		}
+	}
+	return best
+}
+
+func (srch *search) root(state engine.State, moves []engine.Move, depth int) (engine.Move, int, bool) {
+	me := state.Turn()
+	best := moves[0]
+	alpha := -infinity
+	for _, m := range moves {
+		score := -srch.negamax(after(state, m), me.Other(), depth-1, 1, -infinity, -alpha)
+		if srch.timedOut {
+			return best, alpha, false
+		}
+		if score > alpha {
+			alpha = score
+			best = m
+		}
+	}
+	return best, alpha, true
+}
+
+func (srch *search) negamax(state engine.State, side engine.Side, depth, ply, alpha, beta int) int {
+	srch.nodes++
+	if srch.nodes%1024 == 0 && time.Now().After(srch.deadline) {
+		srch.timedOut = true
+	}
+	if srch.timedOut {
+		return 0
+	}
+
+	if outcome := state.Outcome(); outcome.Over() {
+		if winner, ok := outcome.Winner(); ok {
+			if winner == side {
+				return winScore - ply
+			}
+			return -winScore + ply
+		}
+		return 0
+	}
+	if depth == 0 {
+		srch.cutoff = true
+		return 0
+	}
+
+	for _, m := range candidateMoves(state) {
+		score := -srch.negamax(after(state, m), side.Other(), depth-1, ply+1, -beta, -alpha)
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return alpha
+}
@@ -0,0 +1,56 @@
+package engine
+
+import "sync"
+
+const (
+	MinSize = 3
+	MaxSize = 19
+)
+
+// geometry lists every window of winLength consecutive cells on a board,
+// so positions can track line progress incrementally instead of rescanning
+// the whole board after each move.
+type geometry struct {
+	size        int
+	winLength   int
+	windows     [][]int
+	cellWindows [][]int
+}
+
+var geometries sync.Map
+
+func geometryFor(size, winLength int) *geometry {
+	key := [2]int{size, winLength}
+	if g, ok := geometries.Load(key); ok {
+		return g.(*geometry)
+	}
+
+	g := &geometry{
+		size:        size,
+		winLength:   winLength,
+		cellWindows: make([][]int, size*size),
+	}
+	directions := [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			for _, d := range directions {
+				endRow := row + d[0]*(winLength-1)
+				endCol := col + d[1]*(winLength-1)
+				if endRow < 0 || endRow >= size || endCol < 0 || endCol >= size {
+					continue
+				}
+				window := make([]int, winLength)
+				for i := range window {
+					window[i] = (row+d[0]*i)*size + col + d[1]*i
+				}
+				for _, cell := range window {
+					g.cellWindows[cell] = append(g.cellWindows[cell], len(g.windows))
+				}
+				g.windows = append(g.windows, window)
+			}
+		}
+	}
+
+	actual, _ := geometries.LoadOrStore(key, g)
+	return actual.(*geometry)
+}
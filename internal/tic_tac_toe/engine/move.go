@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Move struct {
+	Row    int
+	Col    int
+	Symbol string
+}
+
+func ParseMove(s string, size int) (Move, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 2 || len(s) > 3 {
+		return Move{}, fmt.Errorf("move must be a row letter followed by a column number (e.g., A1)")
+	}
+	row := int(s[0]) - 'A'
+	col, err := strconv.Atoi(s[1:])
+	if err != nil {
+		return Move{}, fmt.Errorf("move must be a row letter followed by a column number (e.g., A1)")
+	}
+	col--
+	if row < 0 || row >= size {
+		return Move{}, ErrRowOutOfBounds
+	}
+	if col < 0 || col >= size {
+		return Move{}, ErrColOutOfBounds
+	}
+	return Move{Row: row, Col: col}, nil
+}
+
+func (m Move) String() string {
+	return fmt.Sprintf("%c%d%s", 'A'+m.Row, m.Col+1, m.Symbol)
+}
@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	Empty = " "
+	X     = "X"
+	O     = "O"
+)
+
+var (
+	ErrRowOutOfBounds = errors.New("row out of bounds")
+	ErrColOutOfBounds = errors.New("column out of bounds")
+	ErrOccupied       = errors.New("cell already occupied")
+	ErrGameOver       = errors.New("game is already over")
+	ErrSymbol         = errors.New("you can't place that symbol")
+	ErrNoSymbol       = errors.New("add the symbol you want to place (e.g., A1X or A1O)")
+)
+
+type Side int
+
+const (
+	First Side = iota
+	Second
+)
+
+func (s Side) Other() Side {
+	if s == First {
+		return Second
+	}
+	return First
+}
+
+func (s Side) Symbol() string {
+	if s == First {
+		return X
+	}
+	return O
+}
+
+type Outcome int
+
+const (
+	Ongoing Outcome = iota
+	FirstWins
+	SecondWins
+	Draw
+)
+
+func (o Outcome) Over() bool {
+	return o != Ongoing
+}
+
+func (o Outcome) Winner() (Side, bool) {
+	switch o {
+	case FirstWins:
+		return First, true
+	case SecondWins:
+		return Second, true
+	}
+	return First, false
+}
+
+type Position struct {
+	Size      int
+	WinLength int
+	Cells     []string
+	ToMove    Side
+
+	variant GridVariant
+	geo     *geometry
+	// counts holds the number of X and O stones in every window.
+	counts  [][2]int
+	openFor [2]int
+	liveFor [2]int
+	lineFor [2]bool
+	empty   int
+}
+
+func ValidateSettings(size, winLength int) error {
+	if size < MinSize || size > MaxSize {
+		return fmt.Errorf("board size must be between %d and %d", MinSize, MaxSize)
+	}
+	if winLength < 3 || winLength > size {
+		return fmt.Errorf("win length must be between 3 and %d", size)
+	}
+	return nil
+}
+
+func NewPosition(size, winLength int) (*Position, error) {
+	return NewVariantPosition(Classic, size, winLength)
+}
+
+func NewVariantPosition(variant GridVariant, size, winLength int) (*Position, error) {
+	if err := ValidateSettings(size, winLength); err != nil {
+		return nil, err
+	}
+
+	geo := geometryFor(size, winLength)
+	p := &Position{
+		Size:      size,
+		WinLength: winLength,
+		Cells:     make([]string, size*size),
+		ToMove:    First,
+		variant:   variant,
+		geo:       geo,
+		counts:    make([][2]int, len(geo.windows)),
+		openFor:   [2]int{len(geo.windows), len(geo.windows)},
+		empty:     size * size,
+	}
+	for i := range p.Cells {
+		p.Cells[i] = Empty
+	}
+	return p, nil
+}
+
+func NewClassicPosition() *Position {
+	p, _ := NewPosition(3, 3)
+	return p
+}
+
+func (p *Position) Clone() State {
+	c := *p
+	c.Cells = append([]string(nil), p.Cells...)
+	c.counts = append([][2]int(nil), p.counts...)
+	return &c
+}
+
+func (p *Position) At(row, col int) string {
+	return p.Cells[row*p.Size+col]
+}
+
+func (p *Position) Turn() Side {
+	return p.ToMove
+}
+
+func (p *Position) Variant() GridVariant {
+	return p.variant
+}
+
+func (p *Position) ParseMove(s string) (Move, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(p.variant.Symbols(p.ToMove)) == 1 {
+		return ParseMove(s, p.Size)
+	}
+
+	if len(s) < 3 || (s[len(s)-1] != 'X' && s[len(s)-1] != 'O') {
+		return Move{}, ErrNoSymbol
+	}
+	m, err := ParseMove(s[:len(s)-1], p.Size)
+	if err != nil {
+		return Move{}, err
+	}
+	m.Symbol = s[len(s)-1:]
+	return m, nil
+}
+
+func (p *Position) FormatMove(m Move) string {
+	return m.String()
+}
+
+func (p *Position) Validate(m Move) error {
+	if p.Outcome().Over() {
+		return ErrGameOver
+	}
+	if m.Row < 0 || m.Row >= p.Size {
+		return ErrRowOutOfBounds
+	}
+	if m.Col < 0 || m.Col >= p.Size {
+		return ErrColOutOfBounds
+	}
+	if p.At(m.Row, m.Col) != Empty {
+		return ErrOccupied
+	}
+	if _, err := p.symbolFor(m); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *Position) symbolFor(m Move) (string, error) {
+	symbols := p.variant.Symbols(p.ToMove)
+	if m.Symbol == "" {
+		if len(symbols) > 1 {
+			return "", ErrNoSymbol
+		}
+		return symbols[0], nil
+	}
+	for _, symbol := range symbols {
+		if symbol == m.Symbol {
+			return symbol, nil
+		}
+	}
+	return "", ErrSymbol
+}
+
+func (p *Position) Apply(m Move) error {
+	if err := p.Validate(m); err != nil {
+		return err
+	}
+	symbol, _ := p.symbolFor(m)
+	p.place(m.Row*p.Size+m.Col, symbol)
+	p.ToMove = p.ToMove.Other()
+	return nil
+}
+
+func (p *Position) place(cell int, symbol string) {
+	p.Cells[cell] = symbol
+	p.empty--
+
+	idx := symbolIndex(symbol)
+	for _, w := range p.geo.cellWindows[cell] {
+		c := &p.counts[w]
+		switch {
+		case c[idx] == 0 && c[1-idx] == 0:
+			p.liveFor[idx]++
+		case c[idx] == 0:
+			p.liveFor[1-idx]--
+		}
+		c[idx]++
+		if c[idx] == 1 {
+			// The window was still open for the other symbol until now.
+			p.openFor[1-idx]--
+		}
+		if c[idx] == p.WinLength {
+			p.lineFor[idx] = true
+		}
+	}
+}
+
+func symbolIndex(symbol string) int {
+	if symbol == X {
+		return 0
+	}
+	return 1
+}
+
+func (p *Position) LegalMoves() []Move {
+	if p.Outcome().Over() {
+		return nil
+	}
+	symbols := p.variant.Symbols(p.ToMove)
+	moves := make([]Move, 0, p.empty*len(symbols))
+	for i, cell := range p.Cells {
+		if cell != Empty {
+			continue
+		}
+		if len(symbols) == 1 {
+			moves = append(moves, Move{Row: i / p.Size, Col: i % p.Size})
+			continue
+		}
+		for _, symbol := range symbols {
+			moves = append(moves, Move{Row: i / p.Size, Col: i % p.Size, Symbol: symbol})
+		}
+	}
+	return moves
+}
+
+func (p *Position) Outcome() Outcome {
+	return p.variant.Outcome(p)
+}
+
+func (p *Position) HasLine(symbol string) bool {
+	return p.lineFor[symbolIndex(symbol)]
+}
+
+// OpenWindows counts the windows that still contain no stone of the other
+// symbol, i.e. the lines symbol could still complete.
+func (p *Position) OpenWindows(symbol string) int {
+	return p.openFor[symbolIndex(symbol)]
+}
+
+// LiveWindows counts the windows that hold at least one stone of symbol
+// and none of the other symbol.
+func (p *Position) LiveWindows(symbol string) int {
+	return p.liveFor[symbolIndex(symbol)]
+}
+
+func (p *Position) EmptyCells() int {
+	return p.empty
+}
@@ -0,0 +1,239 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+)
+
+func play(t *testing.T, state State, moves ...string) {
+	t.Helper()
+	for _, input := range moves {
+		move, err := state.ParseMove(input)
+		if err != nil {
+			t.Fatalf("parse %s: %v", input, err)
+		}
+		if err := state.Apply(move); err != nil {
+			t.Fatalf("apply %s: %v", input, err)
+		}
+	}
+}
+
+func newState(t *testing.T, name string, size, winLength int) State {
+	t.Helper()
+	variant, ok := LookupVariant(name)
+	if !ok {
+		t.Fatalf("variant %q is not registered", name)
+	}
+	state, err := variant.NewState(size, winLength)
+	if err != nil {
+		t.Fatalf("new %s state: %v", name, err)
+	}
+	return state
+}
+
+func TestParseMove(t *testing.T) {
+	tests := []struct {
+		input string
+		size  int
+		want  Move
+		err   error
+	}{
+		{input: "A1", size: 3, want: Move{Row: 0, Col: 0}},
+		{input: "c3", size: 3, want: Move{Row: 2, Col: 2}},
+		{input: " b2 ", size: 3, want: Move{Row: 1, Col: 1}},
+		{input: "O15", size: 15, want: Move{Row: 14, Col: 14}},
+		{input: "D1", size: 3, err: ErrRowOutOfBounds},
+		{input: "A4", size: 3, err: ErrColOutOfBounds},
+		{input: "A0", size: 3, err: ErrColOutOfBounds},
+		{input: "A", size: 3, err: errAny},
+		{input: "1A", size: 3, err: errAny},
+		{input: "A100", size: 15, err: errAny},
+	}
+	for _, tt := range tests {
+		got, err := ParseMove(tt.input, tt.size)
+		if !matches(err, tt.err) {
+			t.Errorf("ParseMove(%q, %d) error = %v, want %v", tt.input, tt.size, err, tt.err)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("ParseMove(%q, %d) = %+v, want %+v", tt.input, tt.size, got, tt.want)
+		}
+	}
+}
+
+var errAny = errors.New("any error")
+
+func matches(err, want error) bool {
+	switch want {
+	case nil:
+		return err == nil
+	case errAny:
+		return err != nil
+	}
+	return errors.Is(err, want)
+}
+
+func TestPositionParseMoveSymbols(t *testing.T) {
+	tests := []struct {
+		variant string
+		input   string
+		want    Move
+		err     error
+	}{
+		{variant: ClassicName, input: "B2", want: Move{Row: 1, Col: 1}},
+		{variant: WildName, input: "a1o", want: Move{Row: 0, Col: 0, Symbol: O}},
+		{variant: WildName, input: "C3X", want: Move{Row: 2, Col: 2, Symbol: X}},
+		{variant: WildName, input: "A1", err: ErrNoSymbol},
+		{variant: OrderChaosName, input: "F6O", want: Move{Row: 5, Col: 5, Symbol: O}},
+	}
+	for _, tt := range tests {
+		state := newState(t, tt.variant, 3, 3)
+		got, err := state.ParseMove(tt.input)
+		if !matches(err, tt.err) {
+			t.Errorf("%s: ParseMove(%q) error = %v, want %v", tt.variant, tt.input, err, tt.err)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("%s: ParseMove(%q) = %+v, want %+v", tt.variant, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		name      string
+		variant   string
+		size      int
+		winLength int
+		moves     []string
+		want      Outcome
+	}{
+		{name: "empty board", variant: ClassicName, size: 3, winLength: 3, want: Ongoing},
+		{name: "row", variant: ClassicName, size: 3, winLength: 3, moves: []string{"A1", "B1", "A2", "B2", "A3"}, want: FirstWins},
+		{name: "column", variant: ClassicName, size: 3, winLength: 3, moves: []string{"A1", "A2", "B1", "B2", "C3", "C2"}, want: SecondWins},
+		{name: "diagonal", variant: ClassicName, size: 3, winLength: 3, moves: []string{"A1", "A2", "B2", "A3", "C3"}, want: FirstWins},
+		{name: "one side can still win", variant: ClassicName, size: 3, winLength: 3, moves: []string{"A1", "A2", "A3", "B2", "B1", "C1", "C2"}, want: Ongoing},
+		{name: "early draw", variant: ClassicName, size: 3, winLength: 3, moves: []string{"A1", "A2", "A3", "B2", "B1", "C1", "C2", "B3"}, want: Draw},
+		{name: "full board", variant: ClassicName, size: 3, winLength: 3, moves: []string{"A1", "B2", "C3", "A2", "C2", "C1", "A3", "B3", "B1"}, want: Draw},
+		{name: "five in a row", variant: ClassicName, size: 15, winLength: 5, moves: []string{"H4", "A1", "H5", "A2", "H6", "A3", "H7", "A5", "H8"}, want: FirstWins},
+		{name: "four is not enough", variant: ClassicName, size: 15, winLength: 5, moves: []string{"H4", "A1", "H5", "A2", "H6", "A3", "H7"}, want: Ongoing},
+		{name: "misere line loses", variant: MisereName, size: 3, winLength: 3, moves: []string{"A1", "B1", "A2", "B2", "A3"}, want: SecondWins},
+		{name: "wild line wins for the mover", variant: WildName, size: 3, winLength: 3, moves: []string{"A1O", "A2O", "A3O"}, want: FirstWins},
+		{name: "notakto line loses", variant: NotaktoName, moves: []string{"A1", "A2", "A3"}, want: SecondWins},
+		{name: "order wins", variant: OrderChaosName, moves: []string{"A1X", "F1O", "A2X", "F2X", "A3X", "F4O", "A4X", "F6X", "A5X"}, want: FirstWins},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newState(t, tt.variant, tt.size, tt.winLength)
+			play(t, state, tt.moves...)
+			if got := state.Outcome(); got != tt.want {
+				t.Errorf("Outcome() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	state := newState(t, ClassicName, 3, 3)
+	play(t, state, "B2")
+	if err := state.Apply(Move{Row: 1, Col: 1}); !errors.Is(err, ErrOccupied) {
+		t.Errorf("Apply on an occupied cell = %v, want %v", err, ErrOccupied)
+	}
+	if got := state.Turn(); got != Second {
+		t.Errorf("a rejected move changed the turn to %v", got)
+	}
+
+	play(t, state, "A1", "A2", "C1", "C2")
+	if err := state.Apply(Move{Row: 2, Col: 2}); !errors.Is(err, ErrGameOver) {
+		t.Errorf("Apply after the game ended = %v, want %v", err, ErrGameOver)
+	}
+
+	wild := newState(t, WildName, 3, 3)
+	if err := wild.Apply(Move{Row: 0, Col: 0}); !errors.Is(err, ErrNoSymbol) {
+		t.Errorf("wild move without a symbol = %v, want %v", err, ErrNoSymbol)
+	}
+	classic := newState(t, ClassicName, 3, 3)
+	if err := classic.Apply(Move{Row: 0, Col: 0, Symbol: O}); !errors.Is(err, ErrSymbol) {
+		t.Errorf("X placing O = %v, want %v", err, ErrSymbol)
+	}
+}
+
+func TestLegalMoves(t *testing.T) {
+	tests := []struct {
+		name      string
+		variant   string
+		size      int
+		winLength int
+		moves     []string
+		want      int
+	}{
+		{name: "classic", variant: ClassicName, size: 3, winLength: 3, want: 9},
+		{name: "classic after two moves", variant: ClassicName, size: 3, winLength: 3, moves: []string{"A1", "B2"}, want: 7},
+		{name: "large board", variant: ClassicName, size: 15, winLength: 5, want: 225},
+		{name: "wild has both symbols", variant: WildName, size: 3, winLength: 3, want: 18},
+		{name: "over", variant: ClassicName, size: 3, winLength: 3, moves: []string{"A1", "B1", "A2", "B2", "A3"}, want: 0},
+		{name: "ultimate", variant: UltimateName, want: 81},
+		{name: "ultimate sends to a board", variant: UltimateName, moves: []string{"5B2"}, want: 8},
+		{name: "ultimate sends to a corner", variant: UltimateName, moves: []string{"5A1"}, want: 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newState(t, tt.variant, tt.size, tt.winLength)
+			play(t, state, tt.moves...)
+			moves := state.LegalMoves()
+			if len(moves) != tt.want {
+				t.Fatalf("len(LegalMoves()) = %d, want %d", len(moves), tt.want)
+			}
+			for _, move := range moves {
+				if err := state.Validate(move); err != nil {
+					t.Errorf("legal move %s does not validate: %v", state.FormatMove(move), err)
+				}
+			}
+		})
+	}
+}
+
+func TestUltimate(t *testing.T) {
+	u := NewUltimatePosition()
+	move, err := u.ParseMove("5B2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if move != (Move{Row: 4, Col: 4}) {
+		t.Errorf("ParseMove(5B2) = %+v", move)
+	}
+	if got := u.FormatMove(Move{Row: 8, Col: 0}); got != "7C1" {
+		t.Errorf("FormatMove = %s, want 7C1", got)
+	}
+	if _, err := u.ParseMove("B2"); err == nil {
+		t.Error("a move without a board number was accepted while any board may be played")
+	}
+
+	play(t, u, "5A1")
+	if u.Next != 0 {
+		t.Errorf("Next = %d, want 0", u.Next)
+	}
+	if _, err := u.ParseMove("B2"); err != nil {
+		t.Errorf("short move into the highlighted board: %v", err)
+	}
+	if err := u.Apply(Move{Row: 8, Col: 8}); !errors.Is(err, ErrWrongBoard) {
+		t.Errorf("move outside the highlighted board = %v, want %v", err, ErrWrongBoard)
+	}
+
+	// O takes the top-left board, and a move that would send X there lets
+	// X play anywhere instead.
+	play(t, u, "1B1", "4A1", "1B2", "5C3", "9A1", "1C1", "7A1", "1C2", "8A1", "1A1", "1B3")
+	if got := u.BoardResult(0); got != O {
+		t.Errorf("BoardResult(0) = %q, want O", got)
+	}
+	if u.Next != 5 {
+		t.Errorf("Next = %d, want 5", u.Next)
+	}
+	play(t, u, "6A1")
+	if u.Next != AnyBoard {
+		t.Errorf("Next = %d, want any board", u.Next)
+	}
+	if err := u.Validate(Move{Row: 0, Col: 2}); !errors.Is(err, ErrBoardClosed) {
+		t.Errorf("move into a decided board = %v, want %v", err, ErrBoardClosed)
+	}
+}
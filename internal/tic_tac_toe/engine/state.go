@@ -0,0 +1,12 @@
+package engine
+
+type State interface {
+	Turn() Side
+	ParseMove(s string) (Move, error)
+	FormatMove(m Move) string
+	Validate(m Move) error
+	Apply(m Move) error
+	LegalMoves() []Move
+	Outcome() Outcome
+	Clone() State
+}
@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrWrongBoard  = errors.New("you must play in the highlighted board")
+	ErrBoardClosed = errors.New("that board is already decided")
+)
+
+const AnyBoard = -1
+
+// UltimatePosition is a 3x3 grid of classic boards. Moves use global
+// coordinates on the 9x9 grid; the cell a move lands in picks the board
+// the opponent has to play in next.
+type UltimatePosition struct {
+	Boards [9]*Position
+	Next   int
+	ToMove Side
+}
+
+func NewUltimatePosition() *UltimatePosition {
+	u := &UltimatePosition{Next: AnyBoard, ToMove: First}
+	for i := range u.Boards {
+		u.Boards[i] = NewClassicPosition()
+	}
+	return u
+}
+
+func (u *UltimatePosition) Clone() State {
+	c := *u
+	for i, b := range u.Boards {
+		c.Boards[i] = b.Clone().(*Position)
+	}
+	return &c
+}
+
+func (u *UltimatePosition) Turn() Side {
+	return u.ToMove
+}
+
+func (u *UltimatePosition) At(row, col int) string {
+	return u.Boards[(row/3)*3+col/3].At(row%3, col%3)
+}
+
+// BoardResult reports who took a sub-board: X, O, Empty while it is still
+// playable, or "-" once it filled up without a winner.
+func (u *UltimatePosition) BoardResult(board int) string {
+	b := u.Boards[board]
+	switch {
+	case b.lineFor[0]:
+		return X
+	case b.lineFor[1]:
+		return O
+	case b.empty == 0:
+		return "-"
+	}
+	return Empty
+}
+
+func (u *UltimatePosition) ParseMove(s string) (Move, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	board := u.Next
+	if len(s) == 3 {
+		n, err := strconv.Atoi(s[:1])
+		if err != nil || n < 1 || n > 9 {
+			return Move{}, fmt.Errorf("board must be a number from 1 to 9")
+		}
+		board = n - 1
+		s = s[1:]
+	} else if len(s) != 2 || board == AnyBoard {
+		return Move{}, fmt.Errorf("move must be a board number followed by a cell (e.g., 5B2)")
+	}
+
+	cell, err := ParseMove(s, 3)
+	if err != nil {
+		return Move{}, err
+	}
+	return Move{Row: (board/3)*3 + cell.Row, Col: (board%3)*3 + cell.Col}, nil
+}
+
+func (u *UltimatePosition) FormatMove(m Move) string {
+	board := (m.Row/3)*3 + m.Col/3
+	return fmt.Sprintf("%d%c%d", board+1, 'A'+m.Row%3, m.Col%3+1)
+}
+
+func (u *UltimatePosition) Validate(m Move) error {
+	if u.Outcome().Over() {
+		return ErrGameOver
+	}
+	if m.Row < 0 || m.Row >= 9 {
+		return ErrRowOutOfBounds
+	}
+	if m.Col < 0 || m.Col >= 9 {
+		return ErrColOutOfBounds
+	}
+	board := (m.Row/3)*3 + m.Col/3
+	if u.Next != AnyBoard && board != u.Next {
+		return ErrWrongBoard
+	}
+	if u.BoardResult(board) != Empty {
+		return ErrBoardClosed
+	}
+	if u.At(m.Row, m.Col) != Empty {
+		return ErrOccupied
+	}
+	return nil
+}
+
+func (u *UltimatePosition) Apply(m Move) error {
+	if err := u.Validate(m); err != nil {
+		return err
+	}
+	board := (m.Row/3)*3 + m.Col/3
+	u.Boards[board].place((m.Row%3)*3+m.Col%3, u.ToMove.Symbol())
+
+	u.Next = (m.Row%3)*3 + m.Col%3
+	if u.BoardResult(u.Next) != Empty {
+		u.Next = AnyBoard
+	}
+	u.ToMove = u.ToMove.Other()
+	return nil
+}
+
+func (u *UltimatePosition) LegalMoves() []Move {
+	if u.Outcome().Over() {
+		return nil
+	}
+	var moves []Move
+	for board := range u.Boards {
+		if (u.Next != AnyBoard && board != u.Next) || u.BoardResult(board) != Empty {
+			continue
+		}
+		for cell, symbol := range u.Boards[board].Cells {
+			if symbol == Empty {
+				moves = append(moves, Move{Row: (board/3)*3 + cell/3, Col: (board%3)*3 + cell%3})
+			}
+		}
+	}
+	return moves
+}
+
+func (u *UltimatePosition) Outcome() Outcome {
+	var meta [9]string
+	for i := range meta {
+		meta[i] = u.BoardResult(i)
+	}
+
+	openX, openO := false, false
+	for _, pattern := range winPatterns {
+		if meta[pattern[0]] == X && meta[pattern[1]] == X && meta[pattern[2]] == X {
+			return FirstWins
+		}
+		if meta[pattern[0]] == O && meta[pattern[1]] == O && meta[pattern[2]] == O {
+			return SecondWins
+		}
+		blockedX, blockedO := false, false
+		for _, board := range pattern {
+			blockedX = blockedX || meta[board] == O || meta[board] == "-"
+			blockedO = blockedO || meta[board] == X || meta[board] == "-"
+		}
+		openX = openX || !blockedX
+		openO = openO || !blockedO
+	}
+
+	if !openX && !openO {
+		return Draw
+	}
+	for _, result := range meta {
+		if result == Empty {
+			return Ongoing
+		}
+	}
+	return Draw
+}
+
+var winPatterns = [][3]int{
+	{0, 1, 2}, {3, 4, 5}, {6, 7, 8},
+	{0, 3, 6}, {1, 4, 7}, {2, 5, 8},
+	{0, 4, 8}, {2, 4, 6},
+}
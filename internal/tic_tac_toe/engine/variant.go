@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type Variant interface {
+	Name() string
+	Description() string
+	SideName(side Side) string
+	// Board returns the board size and win length the variant is always
+	// played with; fixed is false when players may choose their own.
+	Board() (size, winLength int, fixed bool)
+	NewState(size, winLength int) (State, error)
+}
+
+// GridVariant is a variant played on a single Position; it decides which
+// symbols each side may place and how the counters on the board translate
+// into a result.
+type GridVariant interface {
+	Variant
+	Symbols(side Side) []string
+	Outcome(p *Position) Outcome
+}
+
+var (
+	variantsMu sync.RWMutex
+	variants   = make(map[string]Variant)
+)
+
+func RegisterVariant(v Variant) {
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+
+	if _, exists := variants[v.Name()]; exists {
+		panic(fmt.Sprintf("engine: variant %q registered twice", v.Name()))
+	}
+	variants[v.Name()] = v
+}
+
+func LookupVariant(name string) (Variant, bool) {
+	variantsMu.RLock()
+	defer variantsMu.RUnlock()
+
+	v, ok := variants[name]
+	return v, ok
+}
+
+func Variants() []Variant {
+	variantsMu.RLock()
+	defer variantsMu.RUnlock()
+
+	list := make([]Variant, 0, len(variants))
+	for _, v := range variants {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
@@ -0,0 +1,206 @@
+package engine
+
+const (
+	ClassicName      = "classic"
+	UltimateName     = "ultimate"
+	MisereName       = "misere"
+	WildName         = "wild"
+	NotaktoName      = "notakto"
+	OrderChaosName   = "orderchaos"
+	orderChaosSize   = 6
+	orderChaosWin    = 5
+	notaktoSize      = 3
+	notaktoWinLength = 3
+)
+
+var (
+	Classic    GridVariant = classic{}
+	Misere     GridVariant = misere{}
+	Wild       GridVariant = wild{}
+	Notakto    GridVariant = notakto{}
+	OrderChaos GridVariant = orderChaos{}
+	Ultimate   Variant     = ultimate{}
+)
+
+func init() {
+	RegisterVariant(Classic)
+	RegisterVariant(Ultimate)
+	RegisterVariant(Misere)
+	RegisterVariant(Wild)
+	RegisterVariant(Notakto)
+	RegisterVariant(OrderChaos)
+}
+
+var bothSymbols = []string{X, O}
+
+type classic struct{}
+
+func (classic) Name() string        { return ClassicName }
+func (classic) Description() string { return "get the required number in a row to win" }
+
+func (classic) SideName(side Side) string { return side.Symbol() }
+
+func (classic) Board() (int, int, bool) { return 3, 3, false }
+
+func (v classic) NewState(size, winLength int) (State, error) {
+	return NewVariantPosition(v, size, winLength)
+}
+
+func (classic) Symbols(side Side) []string { return []string{side.Symbol()} }
+
+func (classic) Outcome(p *Position) Outcome {
+	if p.HasLine(X) {
+		return FirstWins
+	}
+	if p.HasLine(O) {
+		return SecondWins
+	}
+	// Once no window holds stones of only one symbol, the game is called
+	// a draw before the board fills up.
+	if p.EmptyCells() == 0 || (p.EmptyCells() < len(p.Cells) && p.LiveWindows(X) == 0 && p.LiveWindows(O) == 0) {
+		return Draw
+	}
+	return Ongoing
+}
+
+type misere struct{}
+
+func (misere) Name() string        { return MisereName }
+func (misere) Description() string { return "misère: completing a line loses" }
+
+func (misere) SideName(side Side) string { return side.Symbol() }
+
+func (misere) Board() (int, int, bool) { return 3, 3, false }
+
+func (v misere) NewState(size, winLength int) (State, error) {
+	return NewVariantPosition(v, size, winLength)
+}
+
+func (misere) Symbols(side Side) []string { return []string{side.Symbol()} }
+
+func (misere) Outcome(p *Position) Outcome {
+	if p.HasLine(X) {
+		return SecondWins
+	}
+	if p.HasLine(O) {
+		return FirstWins
+	}
+	if p.EmptyCells() == 0 || (p.OpenWindows(X) == 0 && p.OpenWindows(O) == 0) {
+		return Draw
+	}
+	return Ongoing
+}
+
+type wild struct{}
+
+func (wild) Name() string        { return WildName }
+func (wild) Description() string { return "wild: place either X or O, completing any line wins" }
+
+func (wild) SideName(side Side) string {
+	if side == First {
+		return "first"
+	}
+	return "second"
+}
+
+func (wild) Board() (int, int, bool) { return 3, 3, false }
+
+func (v wild) NewState(size, winLength int) (State, error) {
+	return NewVariantPosition(v, size, winLength)
+}
+
+func (wild) Symbols(Side) []string { return bothSymbols }
+
+func (wild) Outcome(p *Position) Outcome {
+	if p.HasLine(X) || p.HasLine(O) {
+		return winsFor(p.Turn().Other())
+	}
+	if p.EmptyCells() == 0 || (p.OpenWindows(X) == 0 && p.OpenWindows(O) == 0) {
+		return Draw
+	}
+	return Ongoing
+}
+
+type notakto struct{}
+
+func (notakto) Name() string        { return NotaktoName }
+func (notakto) Description() string { return "Notakto: both place X, completing a line loses" }
+
+func (notakto) SideName(side Side) string {
+	if side == First {
+		return "first"
+	}
+	return "second"
+}
+
+func (notakto) Board() (int, int, bool) { return notaktoSize, notaktoWinLength, true }
+
+func (v notakto) NewState(int, int) (State, error) {
+	return NewVariantPosition(v, notaktoSize, notaktoWinLength)
+}
+
+func (notakto) Symbols(Side) []string { return []string{X} }
+
+func (notakto) Outcome(p *Position) Outcome {
+	if p.HasLine(X) {
+		return winsFor(p.Turn())
+	}
+	if p.EmptyCells() == 0 {
+		return Draw
+	}
+	return Ongoing
+}
+
+type orderChaos struct{}
+
+func (orderChaos) Name() string { return OrderChaosName }
+func (orderChaos) Description() string {
+	return "Order & Chaos: Order wants five in a row of either symbol, Chaos wants to stop it"
+}
+
+func (orderChaos) SideName(side Side) string {
+	if side == First {
+		return "Order"
+	}
+	return "Chaos"
+}
+
+func (orderChaos) Board() (int, int, bool) { return orderChaosSize, orderChaosWin, true }
+
+func (v orderChaos) NewState(int, int) (State, error) {
+	return NewVariantPosition(v, orderChaosSize, orderChaosWin)
+}
+
+func (orderChaos) Symbols(Side) []string { return bothSymbols }
+
+func (orderChaos) Outcome(p *Position) Outcome {
+	if p.HasLine(X) || p.HasLine(O) {
+		return FirstWins
+	}
+	if p.EmptyCells() == 0 || (p.OpenWindows(X) == 0 && p.OpenWindows(O) == 0) {
+		return SecondWins
+	}
+	return Ongoing
+}
+
+type ultimate struct{}
+
+func (ultimate) Name() string { return UltimateName }
+func (ultimate) Description() string {
+	return "ultimate: a 3x3 grid of boards, your cell picks your opponent's board"
+}
+
+func (ultimate) SideName(side Side) string { return side.Symbol() }
+
+func (ultimate) Board() (int, int, bool) { return 9, 3, true }
+
+func (ultimate) NewState(int, int) (State, error) {
+	return NewUltimatePosition(), nil
+}
+
+func winsFor(side Side) Outcome {
+	if side == First {
+		return FirstWins
+	}
+	return SecondWins
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"tic_tac_toe/internal/tic_tac_toe/solver"
+)
+
+func handleHint(g *models.Game, s *models.Server) error {
+	if g.Rated {
+		return sendMessageToPlayer(g.CurrentPlayer, "Hints are disabled in rated games.\r\n")
+	}
+
+	analysis, err := s.Solver.Analyze(g.Position)
+	if err != nil {
+		return sendMessageToPlayer(g.CurrentPlayer, fmt.Sprintf("No hint available: %s.\r\n", err.Error()))
+	}
+	return sendMessageToPlayer(g.CurrentPlayer, fmt.Sprintf("Hint: play %s (%s).\r\n",
+		formatMoves(g.Position, analysis.BestMoves), analysis.Evaluation))
+}
+
+func handleAnalyze(g *models.Game, s *models.Server) error {
+	if g.Rated {
+		return sendMessageToPlayer(g.CurrentPlayer, "Analysis is disabled in rated games.\r\n")
+	}
+
+	analysis, err := s.Solver.Analyze(g.Position)
+	if err != nil {
+		return sendMessageToPlayer(g.CurrentPlayer, fmt.Sprintf("Cannot analyze this position: %s.\r\n", err.Error()))
+	}
+	return sendMessageToPlayer(g.CurrentPlayer, describeAnalysis(g.Variant, g.Position, analysis))
+}
+
+func handleAnalyzeRequest(s *models.Server, conn net.Conn, args []string) error {
+	variant := engine.Variant(engine.Classic)
+	if len(args) > 0 {
+		if v, ok := engine.LookupVariant(strings.ToLower(args[0])); ok {
+			variant = v
+			args = args[1:]
+		}
+	}
+
+	size, winLength, _ := variant.Board()
+	state, err := variant.NewState(size, winLength)
+	if err != nil {
+		return trySendMessage(conn, fmt.Sprintf("Cannot analyze: %s.\r\n", err.Error()))
+	}
+	for _, input := range args {
+		move, err := state.ParseMove(input)
+		if err == nil {
+			err = state.Apply(move)
+		}
+		if err != nil {
+			return trySendMessage(conn, fmt.Sprintf("Invalid move %s: %s.\r\n", input, err.Error()))
+		}
+	}
+
+	if err := trySendMessage(conn, renderBoard(state)); err != nil {
+		return err
+	}
+	if state.Outcome().Over() {
+		return trySendMessage(conn, "The game is already over.\r\n")
+	}
+
+	analysis, err := s.Solver.Analyze(state)
+	if err != nil {
+		return trySendMessage(conn, fmt.Sprintf("Cannot analyze this position: %s.\r\n", err.Error()))
+	}
+	return trySendMessage(conn, describeAnalysis(variant, state, analysis))
+}
+
+func describeAnalysis(variant engine.Variant, state engine.State, analysis solver.Analysis) string {
+	var b strings.Builder
+
+	side := variant.SideName(state.Turn())
+	eval := analysis.Evaluation
+	switch eval.Value {
+	case solver.Win:
+		b.WriteString(fmt.Sprintf("Forced win: '%s' to move wins within %d move(s), counting both players.\r\n", side, eval.Plies))
+	case solver.Loss:
+		b.WriteString(fmt.Sprintf("Forced loss: '%s' to move loses within %d move(s) against best play, counting both players.\r\n", side, eval.Plies))
+	default:
+		b.WriteString("Forced draw: neither side can win against best play.\r\n")
+	}
+	b.WriteString(fmt.Sprintf("Best move(s): %s\r\n", formatMoves(state, analysis.BestMoves)))
+
+	b.WriteString("All moves:\r\n")
+	for _, m := range analysis.Moves {
+		b.WriteString(fmt.Sprintf("  %-6s %s\r\n", state.FormatMove(m.Move), m.Evaluation))
+	}
+	return b.String()
+}
+
+func formatMoves(state engine.State, moves []engine.Move) string {
+	formatted := make([]string, len(moves))
+	for i, m := range moves {
+		formatted[i] = state.FormatMove(m)
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+type apiGame struct {
+	ID          string           `json:"id"`
+	Variant     string           `json:"variant"`
+	Size        int              `json:"size"`
+	WinLength   int              `json:"win_length"`
+	TimeMs      int64            `json:"time_ms,omitempty"`
+	IncrementMs int64            `json:"increment_ms,omitempty"`
+	Rated       bool             `json:"rated"`
+	Tournament  string           `json:"tournament,omitempty"`
+	Players     []protocolPlayer `json:"players"`
+	Moves       int              `json:"moves"`
+	ToMove      string           `json:"to_move,omitempty"`
+	Started     time.Time        `json:"started"`
+}
+
+type apiGameBoard struct {
+	apiGame
+	Rows      []string `json:"rows"`
+	NextBoard *int     `json:"next_board,omitempty"`
+	ClockMs   []int64  `json:"clock_ms,omitempty"`
+	History   []string `json:"history"`
+}
+
+type apiPlayerStats struct {
+	Nickname      string  `json:"nickname"`
+	Games         int     `json:"games"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	Draws         int     `json:"draws"`
+	TimeoutLosses int     `json:"timeout_losses"`
+	Resignations  int     `json:"resignations"`
+	WinRate       float64 `json:"win_rate"`
+	Rating        float64 `json:"rating"`
+	Deviation     float64 `json:"deviation"`
+	RatedGames    int     `json:"rated_games"`
+}
+
+type apiLeaderboardEntry struct {
+	Rank       int     `json:"rank"`
+	Nickname   string  `json:"nickname"`
+	Rating     float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`
+	RatedGames int     `json:"rated_games"`
+}
+
+type apiLeaderboard struct {
+	MinGames int                   `json:"min_games"`
+	Players  []apiLeaderboardEntry `json:"players"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// handleAPIGames lists the running games that spectators can see.
+func handleAPIGames(s *models.Server, w http.ResponseWriter, r *http.Request) {
+	games := []apiGame{}
+	s.ActiveGamesMu.Lock()
+	for _, g := range s.Games {
+		if listedForSpectators(g) {
+			games = append(games, newAPIGame(g))
+		}
+	}
+	s.ActiveGamesMu.Unlock()
+
+	sort.Slice(games, func(i, j int) bool {
+		if !games[i].Started.Equal(games[j].Started) {
+			return games[i].Started.Before(games[j].Started)
+		}
+		return games[i].ID < games[j].ID
+	})
+	writeJSON(w, http.StatusOK, games)
+}
+
+// handleAPIGame returns the current board of one running game. Games in
+// private rooms are found by their room code, as for spectators.
+func handleAPIGame(s *models.Server, w http.ResponseWriter, r *http.Request) {
+	s.ActiveGamesMu.Lock()
+	g, ok := findSpectatableGame(s, r.PathValue("id"))
+	if !ok {
+		s.ActiveGamesMu.Unlock()
+		writeJSON(w, http.StatusNotFound, apiError{Error: "game not found"})
+		return
+	}
+	game := newAPIGameBoard(g)
+	s.ActiveGamesMu.Unlock()
+
+	writeJSON(w, http.StatusOK, game)
+}
+
+func handleAPIPlayer(s *models.Server, w http.ResponseWriter, r *http.Request) {
+	stats, err := LoadPlayerStats(s.DB, r.PathValue("nickname"))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "player not found"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "error retrieving statistics"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, apiPlayerStats{
+		Nickname:      stats.Nickname,
+		Games:         stats.Games,
+		Wins:          stats.Wins,
+		Losses:        stats.Losses,
+		Draws:         stats.Draws,
+		TimeoutLosses: stats.TimeoutLosses,
+		Resignations:  stats.Resignations,
+		WinRate:       stats.WinRate(),
+		Rating:        stats.Rating.Rating,
+		Deviation:     stats.Rating.Deviation,
+		RatedGames:    stats.RatedGames,
+	})
+}
+
+func handleAPILeaderboard(s *models.Server, w http.ResponseWriter, r *http.Request) {
+	players, err := TopPlayers(s.DB, s.RatingMinGames)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "error retrieving leaderboard"})
+		return
+	}
+
+	leaderboard := apiLeaderboard{MinGames: s.RatingMinGames, Players: make([]apiLeaderboardEntry, len(players))}
+	for i, p := range players {
+		leaderboard.Players[i] = apiLeaderboardEntry{
+			Rank:       p.Rank,
+			Nickname:   p.Nickname,
+			Rating:     p.Rating.Rating,
+			Deviation:  p.Rating.Deviation,
+			RatedGames: p.RatedGames,
+		}
+	}
+	writeJSON(w, http.StatusOK, leaderboard)
+}
+
+// newAPIGame describes a running game. The caller must hold
+// s.ActiveGamesMu.
+func newAPIGame(g *models.Game) apiGame {
+	game := apiGame{
+		ID:          g.ID,
+		Variant:     g.Settings.Variant,
+		Size:        g.Settings.Size,
+		WinLength:   g.Settings.WinLength,
+		TimeMs:      g.Settings.TimeControl.Base.Milliseconds(),
+		IncrementMs: g.Settings.TimeControl.Increment.Milliseconds(),
+		Rated:       g.Rated,
+		Tournament:  g.Settings.Tournament,
+		Players: []protocolPlayer{
+			{Nickname: g.Player1.NickName, Symbol: g.Player1.Symbol, Bot: g.Player1.Bot != nil},
+			{Nickname: g.Player2.NickName, Symbol: g.Player2.Symbol, Bot: g.Player2.Bot != nil},
+		},
+		Moves:   len(g.Moves),
+		Started: g.Started,
+	}
+	if !g.Position.Outcome().Over() {
+		game.ToMove = playerForSide(g, g.Position.Turn()).NickName
+	}
+	return game
+}
+
+// newAPIGameBoard describes a running game with its board and moves. The
+// caller must hold s.ActiveGamesMu.
+func newAPIGameBoard(g *models.Game) apiGameBoard {
+	board := newBoardEvent(g)
+	game := apiGameBoard{
+		apiGame:   newAPIGame(g),
+		Rows:      board.Rows,
+		NextBoard: board.NextBoard,
+		ClockMs:   board.ClockMs,
+		History:   make([]string, len(g.Moves)),
+	}
+	for i, move := range g.Moves {
+		game.History[i] = move.Notation
+	}
+	return game
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("error writing HTTP response: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+)
+
+func renderBoard(state engine.State) string {
+	switch pos := state.(type) {
+	case *engine.UltimatePosition:
+		return getUltimateBoard(pos)
+	case *engine.Position:
+		return getBoard(pos)
+	}
+	return ""
+}
+
+func movePrompt(state engine.State) string {
+	if u, ok := state.(*engine.UltimatePosition); ok {
+		if u.Next != engine.AnyBoard {
+			return fmt.Sprintf("Your move in board %d (format: B2, or board and cell like %dB2): ", u.Next+1, u.Next+1)
+		}
+		return "Your move in any open board (format: board and cell, e.g. 5B2): "
+	}
+	if p, ok := state.(*engine.Position); ok && len(p.Variant().Symbols(p.Turn())) > 1 {
+		return "Your move (format: cell and symbol, e.g. A1X or B3O): "
+	}
+	return "Your move (format: A1, B3, etc.): "
+}
+
+func getBoard(pos *engine.Position) string {
+	var boardStr strings.Builder
+
+	boardStr.WriteString("\r\n")
+	for j := 0; j < pos.Size; j++ {
+		boardStr.WriteString(fmt.Sprintf("%4d", j+1))
+	}
+	boardStr.WriteString("\r\n")
+
+	for i := 0; i < pos.Size; i++ {
+		boardStr.WriteString(fmt.Sprintf("%c ", 'A'+i))
+		for j := 0; j < pos.Size; j++ {
+			boardStr.WriteString(" " + pos.At(i, j) + " ")
+			if j < pos.Size-1 {
+				boardStr.WriteString("|")
+			}
+		}
+		boardStr.WriteString("\r\n")
+
+		if i < pos.Size-1 {
+			boardStr.WriteString("  " + strings.Repeat("-", 4*pos.Size-1) + "\r\n")
+		}
+	}
+	boardStr.WriteString("\r\n")
+
+	return boardStr.String()
+}
+
+func getUltimateBoard(pos *engine.UltimatePosition) string {
+	const width = 17
+	var boardStr strings.Builder
+
+	boardStr.WriteString("\r\n")
+	for metaRow := 0; metaRow < 3; metaRow++ {
+		for metaCol := 0; metaCol < 3; metaCol++ {
+			board := metaRow*3 + metaCol
+			title := fmt.Sprintf("Board %d", board+1)
+			switch result := pos.BoardResult(board); {
+			case result == "-":
+				title += " (draw)"
+			case result != engine.Empty:
+				title += " (" + result + ")"
+			case pos.Next == board:
+				title += " *"
+			}
+			boardStr.WriteString(fmt.Sprintf("%-*s", width, title))
+		}
+		boardStr.WriteString("\r\n")
+		boardStr.WriteString(strings.Repeat(fmt.Sprintf("%-*s", width, "   1   2   3"), 3) + "\r\n")
+
+		for i := 0; i < 3; i++ {
+			for metaCol := 0; metaCol < 3; metaCol++ {
+				var line strings.Builder
+				line.WriteString(fmt.Sprintf("%c ", 'A'+i))
+				for j := 0; j < 3; j++ {
+					line.WriteString(" " + pos.At(metaRow*3+i, metaCol*3+j) + " ")
+					if j < 2 {
+						line.WriteString("|")
+					}
+				}
+				boardStr.WriteString(fmt.Sprintf("%-*s", width, line.String()))
+			}
+			boardStr.WriteString("\r\n")
+
+			if i < 2 {
+				boardStr.WriteString(strings.Repeat(fmt.Sprintf("%-*s", width, "  -----------"), 3) + "\r\n")
+			}
+		}
+		boardStr.WriteString("\r\n")
+	}
+
+	if !pos.Outcome().Over() {
+		if pos.Next == engine.AnyBoard {
+			boardStr.WriteString("Next move: any open board\r\n")
+		} else {
+			boardStr.WriteString(fmt.Sprintf("Next move: board %d\r\n", pos.Next+1))
+		}
+	}
+
+	return boardStr.String()
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/bot"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+)
+
+func handleBotRequest(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string) error {
+	settings, err := requestSettings(conn, reader)
+	if err != nil {
+		return err
+	}
+
+	b, err := requestBotLevel(s, conn, reader)
+	if err != nil {
+		return err
+	}
+
+	player := models.Player{
+		IP:       conn.RemoteAddr().String(),
+		Conn:     conn,
+		NickName: nickname,
+		Settings: settings,
+	}
+	startBotGame(s, player, b)
+	return nil
+}
+
+func requestBotLevel(s *models.Server, conn net.Conn, reader *bufio.Reader) (bot.Player, error) {
+	prompt := fmt.Sprintf("Choose difficulty (%s): ", strings.Join(bot.Levels(), ", "))
+	for {
+		if err := trySendMessage(conn, prompt); err != nil {
+			return nil, err
+		}
+
+		level, err := tryReadMessage(conn, reader)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := bot.New(level, s.BotOptions)
+		if err == nil {
+			return b, nil
+		}
+		if err := trySendMessage(conn, fmt.Sprintf("%s.\r\n", err.Error())); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func fillWithBot(s *models.Server, player models.Player) {
+	b, err := bot.New(s.BotFillLevel, s.BotOptions)
+	if err != nil {
+		log.Printf("invalid bot fill level, falling back to %s: %v", bot.HeuristicLevel, err)
+		b = &bot.Heuristic{}
+	}
+
+	log.Printf("no opponent for %s after %s, pairing with a bot", player.NickName, s.BotFillTimeout)
+
+	if err := trySendMessage(player.Conn, "No opponent showed up, so you will play against a computer player.\r\n"); err != nil {
+		handleLogout(s, player.NickName, player.Conn)
+		return
+	}
+	startBotGame(s, player, b)
+}
+
+func startBotGame(s *models.Server, player models.Player, b bot.Player) {
+	opponent := models.Player{
+		NickName: fmt.Sprintf("Bot (%s)", b.Name()),
+		Settings: player.Settings,
+		Bot:      b,
+	}
+
+	variant, ok := engine.LookupVariant(player.Settings.Variant)
+	if !ok {
+		variant = engine.Classic
+	}
+
+	player1, player2 := player, opponent
+	if rand.Intn(2) == 1 {
+		player1, player2 = opponent, player
+	}
+	player1.Symbol = variant.SideName(engine.First)
+	player2.Symbol = variant.SideName(engine.Second)
+
+	log.Printf("creating %s game with %s and %s", describeSettings(player.Settings), player1.NickName, player2.NickName)
+
+	StartGame(player1, player2, variant, s)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+const challengeTimeout = 2 * time.Minute
+
+// setInLobby records whether the player is at the menu, where invitations
+// can reach them.
+func setInLobby(s *models.Server, nickname string, conn net.Conn, inLobby bool) {
+	s.ActiveUsersMu.Lock()
+	defer s.ActiveUsersMu.Unlock()
+
+	if session, ok := s.ActiveUsers[nickname]; ok && session.Conn == conn {
+		session.InLobby = inLobby
+	}
+}
+
+func handleWhoRequest(s *models.Server, conn net.Conn) error {
+	type online struct {
+		nickname string
+		conn     net.Conn
+		inLobby  bool
+	}
+
+	s.ActiveUsersMu.Lock()
+	users := make([]online, 0, len(s.ActiveUsers))
+	for nickname, session := range s.ActiveUsers {
+		users = append(users, online{nickname: nickname, conn: session.Conn, inLobby: session.InLobby})
+	}
+	s.ActiveUsersMu.Unlock()
+
+	sort.Slice(users, func(i, j int) bool { return users[i].nickname < users[j].nickname })
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("\r\n%d player(s) online:\r\n", len(users)))
+	for _, user := range users {
+		status := "busy"
+		switch {
+		case user.conn == nil:
+			status = "disconnected"
+		case user.inLobby:
+			status = "in the lobby"
+		case findGame(s, user.nickname) != nil:
+			status = "playing"
+		}
+		builder.WriteString(fmt.Sprintf("  %-20s %s\r\n", user.nickname, status))
+	}
+	return trySendMessage(conn, builder.String())
+}
+
+// handleChallengeRequest invites another player in the lobby to a game and
+// waits for the answer. It reports whether a game was started, in which
+// case the connection now belongs to that game.
+func handleChallengeRequest(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string, args []string) (bool, error) {
+	if len(args) != 1 {
+		return false, trySendMessage(conn, "Usage: challenge <nickname>\r\n")
+	}
+	opponent := args[0]
+	if opponent == nickname {
+		return false, trySendMessage(conn, "You cannot challenge yourself.\r\n")
+	}
+	if !inLobby(s, opponent) {
+		return false, trySendMessage(conn, fmt.Sprintf("%s is not in the lobby right now. Type 'who' to see who is.\r\n", opponent))
+	}
+
+	settings, err := requestSettings(conn, reader)
+	if err != nil {
+		return false, err
+	}
+	settings.Rated, err = requestRated(conn, reader)
+	if err != nil {
+		return false, err
+	}
+
+	challenge := &models.Challenge{
+		From: models.Player{
+			IP:       conn.RemoteAddr().String(),
+			Conn:     conn,
+			NickName: nickname,
+			Settings: settings,
+		},
+		To:       opponent,
+		Settings: settings,
+		Created:  time.Now(),
+		Answer:   make(chan *models.Player, 1),
+	}
+
+	s.ChallengesMu.Lock()
+	s.Challenges[nickname] = challenge
+	s.ChallengesMu.Unlock()
+
+	notifyPlayer(s, opponent, fmt.Sprintf("\r\n%s challenges you to a game (%s). Type 'accept %s' or 'decline %s'.\r\n", nickname, describeSettings(settings), nickname, nickname))
+	if err := trySendMessage(conn, fmt.Sprintf("Challenge sent to %s. Waiting up to %s for an answer (type 'cancel' to withdraw it)...\r\n", opponent, challengeTimeout)); err != nil {
+		withdrawChallenge(s, challenge)
+		return false, err
+	}
+
+	withdraw := func() bool {
+		if !withdrawChallenge(s, challenge) {
+			return false
+		}
+		notifyPlayer(s, opponent, fmt.Sprintf("\r\n%s withdrew their challenge.\r\n", nickname))
+		return true
+	}
+	accepted, err := awaitOpponent(conn, reader, challengeTimeout, challenge.Answer, withdraw, fmt.Sprintf("Still waiting for %s. Type 'cancel' to withdraw the challenge.\r\n", opponent))
+	if err != nil || accepted == nil {
+		return false, err
+	}
+
+	go startDirectGame(s, challenge.From, *accepted)
+	return true, nil
+}
+
+// withdrawChallenge removes the challenge unless the invited player has
+// already answered it, and reports whether it did.
+func withdrawChallenge(s *models.Server, challenge *models.Challenge) bool {
+	s.ChallengesMu.Lock()
+	defer s.ChallengesMu.Unlock()
+
+	if s.Challenges[challenge.From.NickName] != challenge {
+		return false
+	}
+	delete(s.Challenges, challenge.From.NickName)
+	return true
+}
+
+// claimChallenge takes a challenge addressed to nickname off the list. With
+// no challenger named, it only succeeds if there is exactly one.
+func claimChallenge(s *models.Server, nickname string, args []string) (*models.Challenge, string) {
+	s.ChallengesMu.Lock()
+	defer s.ChallengesMu.Unlock()
+
+	var pending []*models.Challenge
+	for _, challenge := range s.Challenges {
+		if challenge.To == nickname && (len(args) == 0 || challenge.From.NickName == args[0]) {
+			pending = append(pending, challenge)
+		}
+	}
+
+	switch {
+	case len(pending) == 0 && len(args) == 0:
+		return nil, "Nobody has challenged you.\r\n"
+	case len(pending) == 0:
+		return nil, fmt.Sprintf("%s has not challenged you.\r\n", args[0])
+	case len(pending) > 1:
+		names := make([]string, len(pending))
+		for i, challenge := range pending {
+			names[i] = challenge.From.NickName
+		}
+		sort.Strings(names)
+		return nil, fmt.Sprintf("You have been challenged by %s. Name the one you mean.\r\n", strings.Join(names, ", "))
+	}
+
+	delete(s.Challenges, pending[0].From.NickName)
+	return pending[0], ""
+}
+
+func handleAcceptRequest(s *models.Server, conn net.Conn, nickname string, args []string) (bool, error) {
+	challenge, problem := claimChallenge(s, nickname, args)
+	if challenge == nil {
+		return false, trySendMessage(conn, problem)
+	}
+
+	challenge.Answer <- &models.Player{
+		IP:       conn.RemoteAddr().String(),
+		Conn:     conn,
+		NickName: nickname,
+		Settings: challenge.Settings,
+	}
+	return true, nil
+}
+
+func handleDeclineRequest(s *models.Server, conn net.Conn, nickname string, args []string) error {
+	challenge, problem := claimChallenge(s, nickname, args)
+	if challenge == nil {
+		return trySendMessage(conn, problem)
+	}
+
+	if _, err := challenge.From.Conn.Write([]byte(fmt.Sprintf("%s declined your challenge.\r\n", nickname))); err != nil {
+		log.Printf("error notifying %s: %v", challenge.From.NickName, err)
+	}
+	challenge.Answer <- nil
+	return trySendMessage(conn, fmt.Sprintf("You declined the challenge from %s.\r\n", challenge.From.NickName))
+}
+
+func inLobby(s *models.Server, nickname string) bool {
+	s.ActiveUsersMu.Lock()
+	defer s.ActiveUsersMu.Unlock()
+
+	session, ok := s.ActiveUsers[nickname]
+	return ok && session.Conn != nil && session.InLobby
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+var errFlagFell = errors.New("ran out of time")
+
+func newClock(tc models.TimeControl) *models.Clock {
+	if !tc.Enabled() {
+		return nil
+	}
+	return &models.Clock{
+		Remaining: [2]time.Duration{tc.Base, tc.Base},
+		Increment: tc.Increment,
+	}
+}
+
+func clockIndex(g *models.Game, player *models.Player) int {
+	if player == &g.Player1 {
+		return 0
+	}
+	return 1
+}
+
+func timeLeft(g *models.Game, player *models.Player, turnStarted time.Time) time.Duration {
+	return g.Clock.Remaining[clockIndex(g, player)] - time.Since(turnStarted)
+}
+
+// chargeClock stops the clock of the player who just moved and adds the
+// Fischer increment.
+func chargeClock(g *models.Game, player *models.Player, turnStarted time.Time) {
+	if g.Clock == nil {
+		return
+	}
+	i := clockIndex(g, player)
+	g.Clock.Remaining[i] -= time.Since(turnStarted)
+	g.Clock.Remaining[i] += g.Clock.Increment
+}
+
+// botThinkBudget is the share of its remaining time a bot may spend on a
+// single move.
+func botThinkBudget(remaining time.Duration) time.Duration {
+	return remaining / 10
+}
+
+func formatClock(g *models.Game) string {
+	if g.Clock == nil {
+		return ""
+	}
+	return fmt.Sprintf("Clock: %s %s | %s %s\r\n",
+		g.Player1.NickName, formatDuration(g.Clock.Remaining[0]),
+		g.Player2.NickName, formatDuration(g.Clock.Remaining[1]))
+}
+
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+func describeTimeControl(tc models.TimeControl) string {
+	if !tc.Enabled() {
+		return "no clock"
+	}
+	return fmt.Sprintf("%s+%ds", formatDuration(tc.Base), int(tc.Increment.Seconds()))
+}
+
+func requestTimeControl(conn net.Conn, reader *bufio.Reader) (models.TimeControl, error) {
+	for {
+		if err := trySendMessage(conn, "Enter minutes per player and increment seconds (e.g. '5 3'), or press enter for no clock: "); err != nil {
+			return models.TimeControl{}, err
+		}
+
+		input, err := tryReadMessage(conn, reader)
+		if err != nil {
+			return models.TimeControl{}, err
+		}
+
+		tc, err := parseTimeControl(input)
+		if err != nil {
+			if err := trySendMessage(conn, fmt.Sprintf("Invalid time control: %s.\r\n", err.Error())); err != nil {
+				return models.TimeControl{}, err
+			}
+			continue
+		}
+		return tc, nil
+	}
+}
+
+func parseTimeControl(input string) (models.TimeControl, error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return models.TimeControl{}, nil
+	}
+	if len(fields) > 2 {
+		return models.TimeControl{}, fmt.Errorf("expected minutes and optional increment")
+	}
+
+	minutes, err := strconv.ParseFloat(fields[0], 64)
+	tc := models.TimeControl{Base: time.Duration(minutes * float64(time.Minute)).Round(time.Second)}
+	if err != nil || tc.Base < time.Second || minutes > 180 {
+		return models.TimeControl{}, fmt.Errorf("minutes must be a number greater than 0 and at most 180")
+	}
+
+	if len(fields) == 2 {
+		seconds, err := strconv.Atoi(fields[1])
+		if err != nil || seconds < 0 || seconds > 60 {
+			return models.TimeControl{}, fmt.Errorf("increment must be a number of seconds between 0 and 60")
+		}
+		tc.Increment = time.Duration(seconds) * time.Second
+	}
+	return tc, nil
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"testing"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    models.TimeControl
+		wantErr bool
+	}{
+		{input: "", want: models.TimeControl{}},
+		{input: "5", want: models.TimeControl{Base: 5 * time.Minute}},
+		{input: "5 3", want: models.TimeControl{Base: 5 * time.Minute, Increment: 3 * time.Second}},
+		{input: "0.5 0", want: models.TimeControl{Base: 30 * time.Second}},
+		{input: "180 60", want: models.TimeControl{Base: 180 * time.Minute, Increment: 60 * time.Second}},
+		{input: "0", wantErr: true},
+		{input: "0.001", wantErr: true},
+		{input: "0.001 5", wantErr: true},
+		{input: "181", wantErr: true},
+		{input: "5 61", wantErr: true},
+		{input: "5 -1", wantErr: true},
+		{input: "five", wantErr: true},
+		{input: "5 3 1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeControl(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimeControl(%q) error = %v, want error %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("parseTimeControl(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+const takebackAnswerTimeout = 30 * time.Second
+
+var (
+	errResigned   = errors.New("player resigned")
+	errDrawAgreed = errors.New("draw agreed")
+)
+
+// handleGameCommand runs a command typed instead of a move. turnStarted is
+// the start of the current player's turn and is moved forward by any time
+// the command spent waiting on the opponent.
+func handleGameCommand(g *models.Game, s *models.Server, input string, turnStarted *time.Time) (bool, error) {
+	switch input {
+	case "hint":
+		return true, handleHint(g, s)
+	case "analyze":
+		return true, handleAnalyze(g, s)
+	case "resign":
+		return true, errResigned
+	case "draw":
+		return true, offerDraw(g)
+	case "accept":
+		if g.DrawOffer != g.WaitingPlayer {
+			return true, sendMessageToPlayer(g.CurrentPlayer, "There is no draw offer to accept.\r\n")
+		}
+		return true, errDrawAgreed
+	case "decline":
+		if g.DrawOffer != g.WaitingPlayer {
+			return true, sendMessageToPlayer(g.CurrentPlayer, "There is no draw offer to decline.\r\n")
+		}
+		return true, declineDraw(g)
+	case "undo":
+		return true, requestTakeback(g, s, turnStarted)
+	}
+	return false, nil
+}
+
+func offerDraw(g *models.Game) error {
+	switch {
+	case g.DrawOffer == g.WaitingPlayer:
+		return errDrawAgreed
+	case g.DrawOffer == g.CurrentPlayer:
+		return sendMessageToPlayer(g.CurrentPlayer, "You have already offered a draw.\r\n")
+	case g.WaitingPlayer.Bot != nil:
+		return sendMessageToPlayer(g.CurrentPlayer, fmt.Sprintf("%s declines the draw offer.\r\n", g.WaitingPlayer.NickName))
+	}
+
+	g.DrawOffer = g.CurrentPlayer
+	notifySpectators(g, fmt.Sprintf("%s offers a draw.\r\n", g.CurrentPlayer.NickName))
+	if err := sendMessageToPlayer(g.WaitingPlayer, fmt.Sprintf("%s offers a draw. You can accept or decline it on your turn.\r\n", g.CurrentPlayer.NickName)); err != nil {
+		return err
+	}
+	return sendMessageToPlayer(g.CurrentPlayer, "Draw offered. Your opponent will answer on their turn, now make your move.\r\n")
+}
+
+func declineDraw(g *models.Game) error {
+	g.DrawOffer = nil
+	message := fmt.Sprintf("%s declines the draw offer.\r\n", g.CurrentPlayer.NickName)
+	notifySpectators(g, message)
+	if err := sendMessageToPlayer(g.WaitingPlayer, message); err != nil {
+		return err
+	}
+	return sendMessageToPlayer(g.CurrentPlayer, "Draw offer declined.\r\n")
+}
+
+// requestTakeback asks the opponent right away whether the current player
+// may take back their last move, together with the opponent's reply to it.
+// The requester's clock is paused while the opponent decides, and the wait
+// never outlasts the time the requester has left.
+func requestTakeback(g *models.Game, s *models.Server, turnStarted *time.Time) error {
+	if len(g.Moves) < 2 {
+		return sendMessageToPlayer(g.CurrentPlayer, "You have no move to take back yet.\r\n")
+	}
+	if g.WaitingPlayer.Bot != nil {
+		return sendMessageToPlayer(g.CurrentPlayer, fmt.Sprintf("%s declines the takeback.\r\n", g.WaitingPlayer.NickName))
+	}
+
+	if err := sendMessageToPlayer(g.CurrentPlayer, "Asking your opponent...\r\n"); err != nil {
+		return err
+	}
+	if err := sendMessageToPlayer(g.WaitingPlayer, fmt.Sprintf("%s requests a takeback of their last move. Type 'accept' or 'decline': ", g.CurrentPlayer.NickName)); err != nil {
+		return err
+	}
+	wait := takebackAnswerTimeout
+	if g.Clock != nil {
+		wait = min(wait, timeLeft(g, g.CurrentPlayer, *turnStarted))
+	}
+	asked := time.Now()
+	accepted, err := readTakebackAnswer(g.WaitingPlayer, wait)
+	*turnStarted = turnStarted.Add(time.Since(asked))
+	if err != nil {
+		return err
+	}
+
+	if !accepted {
+		message := fmt.Sprintf("%s declines the takeback.\r\n", g.WaitingPlayer.NickName)
+		notifySpectators(g, message)
+		if err := sendMessageToPlayer(g.WaitingPlayer, message); err != nil {
+			return err
+		}
+		return sendMessageToPlayer(g.CurrentPlayer, message)
+	}
+
+	position, err := replayMoves(g, g.Moves[:len(g.Moves)-2])
+	if err != nil {
+		return err
+	}
+	s.ActiveGamesMu.Lock()
+	g.Position = position
+	g.Moves = g.Moves[:len(g.Moves)-2]
+	s.ActiveGamesMu.Unlock()
+	g.DrawOffer = nil
+
+	message := fmt.Sprintf("%s accepts the takeback.\r\n", g.WaitingPlayer.NickName)
+	board := renderBoard(g.Position)
+	notifySpectators(g, message)
+	sendToSpectators(g, board, newBoardEvent(g))
+	if err := sendMessageToPlayer(g.WaitingPlayer, message); err != nil {
+		return err
+	}
+	if err := sendEventToPlayer(g.WaitingPlayer, board+"Waiting for your oponent's turn...\r\n", newBoardEvent(g)); err != nil {
+		return err
+	}
+	if err := sendMessageToPlayer(g.CurrentPlayer, message); err != nil {
+		return err
+	}
+	return sendEventToPlayer(g.CurrentPlayer, board, newBoardEvent(g))
+}
+
+// readTakebackAnswer waits for an explicit answer to a takeback request.
+// Anything else the opponent typed, such as a move sent before the request
+// arrived, is discarded instead of being taken as the answer. No answer
+// within wait declines.
+func readTakebackAnswer(player *models.Player, wait time.Duration) (bool, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false, nil
+		}
+		input, err := readFromPlayer(player, remaining)
+		if isTimeout(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		for _, line := range strings.Split(strings.ToLower(input), "\n") {
+			switch strings.TrimSpace(line) {
+			case "yes", "y", "accept":
+				return true, nil
+			case "no", "n", "decline":
+				return false, nil
+			}
+		}
+		if err := sendMessageToPlayer(player, "Please answer 'accept' or 'decline': "); err != nil {
+			return false, err
+		}
+	}
+}
+
+// replayMoves rebuilds the game's position from the first move, on the
+// board the game is actually played on.
+func replayMoves(g *models.Game, moves []models.MoveRecord) (engine.State, error) {
+	size, winLength := g.Settings.Size, g.Settings.WinLength
+	if pos, ok := g.Position.(*engine.Position); ok {
+		size, winLength = pos.Size, pos.WinLength
+	}
+	position, err := g.Variant.NewState(size, winLength)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range moves {
+		if err := position.Apply(record.Move); err != nil {
+			return nil, err
+		}
+	}
+	return position, nil
+}
@@ -6,24 +6,65 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"tic_tac_toe/internal/tic_tac_toe/bot"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
 	"tic_tac_toe/internal/tic_tac_toe/models"
+	"tic_tac_toe/internal/tic_tac_toe/rating"
+	"tic_tac_toe/internal/tic_tac_toe/solver"
 )
 
-func NewServer(address string, dB *sql.DB) *models.Server {
-	return &models.Server{
-		ListenAddr:  address,
-		ConnsChan:   make(chan models.Player),
-		ResultsChan: make(chan models.GameResult),
-		DB:          dB,
+func NewServer(address string, dB *sql.DB, cfg *models.Config) *models.Server {
+	s := &models.Server{
+		ListenAddr:   address,
+		HTTPAddr:     cfg.HTTPAddr,
+		ConnsChan:    make(chan models.Player),
+		ReattachChan: make(chan models.Reattach),
+		LeaveChan:    make(chan models.LeaveQueue),
+		QueueChan:    make(chan chan []models.QueueStatus),
+		ResultsChan:  make(chan models.GameResult),
+		DB:           dB,
+
+		BotFillTimeout: cfg.BotFillTimeout,
+		BotFillLevel:   cfg.BotFillLevel,
+		Solver:         solver.New(),
+		BotOptions: bot.Options{
+			ThinkTime:     cfg.BotThinkTime,
+			MaxIterations: cfg.BotIterations,
+		},
 
 		ActiveGamesMu: sync.Mutex{},
 		Games:         make(map[string]*models.Game),
 
 		ActiveUsersMu: sync.Mutex{},
-		ActiveUsers:   make(map[string]net.Conn),
+		ActiveUsers:   make(map[string]*models.Session),
+		Sessions:      make(map[string]*models.Session),
+		SessionPolicy: cfg.SessionPolicy,
+
+		ReconnectGrace: cfg.ReconnectGrace,
+		RatingMinGames: cfg.RatingMinGames,
+
+		ChallengesMu: sync.Mutex{},
+		Challenges:   make(map[string]*models.Challenge),
+
+		Admins:            make(map[string]bool),
+		TournamentCheckIn: cfg.TournamentCheckIn,
+		TournamentsMu:     sync.Mutex{},
+		Tournaments:       make(map[string]*models.Tournament),
+
+		RoomsMu: sync.Mutex{},
+		Rooms:   make(map[string]*models.Room),
+
+		RematchMu:     sync.Mutex{},
+		Rematches:     make(map[string]models.Rematch),
+		RematchOffers: make(map[string]*models.RematchOffer),
+	}
+	for _, admin := range cfg.Admins {
+		s.Admins[admin] = true
 	}
+	return s
 }
 
 func ListenAndPair(s *models.Server) error {
@@ -39,6 +80,9 @@ func ListenAndPair(s *models.Server) error {
 
 	go AcceptNewConns(s)
 	go MonitorResults(s)
+	if s.HTTPAddr != "" {
+		go ListenHTTP(s)
+	}
 
 	HandleConns(s)
 
@@ -60,21 +104,40 @@ func AcceptNewConns(s *models.Server) {
 }
 
 func handleNewConn(s *models.Server, conn net.Conn) {
-	if err := trySendMessage(conn, "\r\nEnter: 'login' to authenticate,\r\n       'spectate' to watch or\r\n       'quit' to quit: "); err != nil {
+	handleWelcome(s, conn, bufio.NewReader(conn))
+}
+
+func handleWelcome(s *models.Server, conn net.Conn, reader *bufio.Reader) {
+	if err := sendEvent(conn, "\r\nEnter: 'login' to authenticate,\r\n       'resume <token>' to continue a session,\r\n       'spectate' to watch,\r\n       'replay <game ID>' to step through a finished game,\r\n       'json' to switch to the JSON-lines protocol or\r\n       'quit' to quit: ", nil); err != nil {
 		return
 	}
 
-	reader := bufio.NewReader(conn)
 	choice, err := tryReadMessage(conn, reader)
 	if err != nil {
 		return
 	}
-	choice = strings.TrimSpace(strings.ToLower(choice))
+	args := strings.Fields(strings.ToLower(choice))
+	choice = ""
+	if len(args) > 0 {
+		choice, args = args[0], args[1:]
+	}
 
 	if choice == "login" {
 		handleLogin(s, conn, reader)
+	} else if choice == "resume" && len(args) == 1 {
+		handleResume(s, conn, reader, args[0])
 	} else if choice == "spectate" {
 		handleSpectatorConnection(s, conn, reader)
+	} else if choice == "replay" {
+		handleReplayRequest(s, conn, reader, args)
+		conn.Close()
+	} else if choice == "json" && !isJSONConn(conn) {
+		client, err := switchToJSON(conn, reader)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		handleWelcome(s, client, bufio.NewReader(client))
 	} else if choice == "quit" {
 		conn.Close()
 	} else {
@@ -100,15 +163,6 @@ func handleLogin(s *models.Server, conn net.Conn, reader *bufio.Reader) {
 
 	s.ActiveUsersMu.Lock()
 
-	if _, exists := s.ActiveUsers[nickname]; exists {
-		if err := trySendMessage(conn, "User already logged in. Disconnecting.\r\n"); err != nil {
-			log.Printf("error sending message: %v", err)
-		}
-		conn.Close()
-		s.ActiveUsersMu.Unlock()
-		return
-	}
-
 	for attempt := 0; attempt < 3; attempt++ {
 		succ, err := ProcessNickname(s.DB, conn, reader, nickname)
 		if err != nil {
@@ -117,7 +171,6 @@ func handleLogin(s *models.Server, conn net.Conn, reader *bufio.Reader) {
 			}
 			conn.Close()
 			s.ActiveUsersMu.Unlock()
-			handleLogout(s, nickname)
 			return
 		}
 
@@ -131,7 +184,6 @@ func handleLogin(s *models.Server, conn net.Conn, reader *bufio.Reader) {
 			}
 			conn.Close()
 			s.ActiveUsersMu.Unlock()
-			handleLogout(s, nickname)
 			return
 		}
 		if err := trySendMessage(conn, fmt.Sprintf("Invalid password. Try again. %d attempt(s) left.\r\n", 2-attempt)); err != nil {
@@ -139,18 +191,48 @@ func handleLogin(s *models.Server, conn net.Conn, reader *bufio.Reader) {
 		}
 	}
 
-	s.ActiveUsers[nickname] = conn
+	// The password proves ownership just as a resume token does, so a player
+	// with a game in progress gets back into it even if the connection they
+	// dropped has not been noticed yet.
+	session := s.ActiveUsers[nickname]
+	if sessionInUse(s, session) && findGame(s, nickname) == nil {
+		if err := trySendMessage(conn, "User already logged in. Disconnecting.\r\n"); err != nil {
+			log.Printf("error sending message: %v", err)
+		}
+		conn.Close()
+		s.ActiveUsersMu.Unlock()
+		return
+	}
+	if session != nil {
+		attachSession(session, conn)
+		s.ActiveUsersMu.Unlock()
+		resumeSession(s, conn, reader, nickname)
+		return
+	}
+	session = newSession(s, nickname, conn)
 	s.ActiveUsersMu.Unlock()
 
+	message := fmt.Sprintf("Your session token is %s. Use 'resume %s' to get back here from a new connection.\r\n", session.Token, session.Token)
+	if err := sendEvent(conn, message, sessionEvent{Type: "session", Nickname: nickname, Token: session.Token}); err != nil {
+		handleLogout(s, nickname, conn)
+		return
+	}
+
+	enterLobby(s, conn, reader, nickname)
+}
+
+func enterLobby(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string) {
 	if err := handleBasicCommands(s, conn, reader, nickname); err != nil {
 		conn.Close()
-		handleLogout(s, nickname)
+		handleLogout(s, nickname, conn)
 	}
 }
 
 func handleBasicCommands(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string) error {
 	for {
-		if err := trySendMessage(conn, "\r\nEnter: 'play' to join a game,\r\n       'stats' to view your statistics,\r\n       'top10' to view top 10 players or\r\n       'quit' to quit: "); err != nil {
+		setInLobby(s, nickname, conn, true)
+
+		if err := sendEvent(conn, "\r\nEnter: 'play' to join a game,\r\n       'bot' to play against the computer,\r\n       'rematch' to play your last opponent again,\r\n       'host' to open a private room,\r\n       'join <code>' to join a private room,\r\n       'who' to see who is online,\r\n       'challenge <nickname>' to invite a player in the lobby,\r\n       'accept'/'decline [nickname]' to answer a challenge,\r\n       'queue' to see who is waiting for an opponent,\r\n       'tournament [list|show|join|leave|play]' for tournaments,\r\n       'stats' to view your statistics,\r\n       'history [n]' to list your recent games,\r\n       'replay <game ID>' to step through a finished game,\r\n       'export <game ID>'/'import' to save or load a game as text,\r\n       'top10' to view top 10 players,\r\n       'analyze [variant] [moves]' to solve a position or\r\n       'quit' to quit: ", lobbyEvent{Type: "lobby", Nickname: nickname}); err != nil {
 			return err
 		}
 
@@ -158,20 +240,123 @@ func handleBasicCommands(s *models.Server, conn net.Conn, reader *bufio.Reader,
 		if err != nil {
 			return err
 		}
-		if err := trySendMessage(conn, "\r\n"); err != nil {
+		if err := sendEvent(conn, "\r\n", nil); err != nil {
 			return err
 		}
 
-		choice = strings.TrimSpace(strings.ToLower(choice))
+		args := strings.Fields(choice)
+		choice = ""
+		if len(args) > 0 {
+			choice, args = strings.ToLower(args[0]), args[1:]
+		}
+
+		switch choice {
+		case "play", "bot", "rematch", "host", "join", "challenge", "accept":
+			setInLobby(s, nickname, conn, false)
+		}
 
 		switch choice {
 		case "play":
-			handlePlayerConnection(s, conn, nickname)
+			settings, err := requestSettings(conn, reader)
+			if err == nil {
+				settings.Rated, err = requestRated(conn, reader)
+			}
+			if err != nil {
+				handleLogout(s, nickname, conn)
+				return nil
+			}
+			if !handlePlayerConnection(s, conn, reader, nickname, settings) {
+				return nil
+			}
+		case "bot":
+			if err := handleBotRequest(s, conn, reader, nickname); err != nil {
+				handleLogout(s, nickname, conn)
+			}
 			return nil
+		case "rematch":
+			started, err := handleRematchRequest(s, conn, reader, nickname)
+			if err != nil {
+				return err
+			}
+			if started {
+				return nil
+			}
+		case "host":
+			started, err := handleHostRequest(s, conn, reader, nickname)
+			if err != nil {
+				return err
+			}
+			if started {
+				return nil
+			}
+		case "join":
+			started, err := handleJoinRequest(s, conn, nickname, args)
+			if err != nil {
+				return err
+			}
+			if started {
+				return nil
+			}
+		case "who":
+			if err := handleWhoRequest(s, conn); err != nil {
+				return err
+			}
+		case "challenge":
+			started, err := handleChallengeRequest(s, conn, reader, nickname, args)
+			if err != nil {
+				return err
+			}
+			if started {
+				return nil
+			}
+		case "accept":
+			started, err := handleAcceptRequest(s, conn, nickname, args)
+			if err != nil {
+				return err
+			}
+			if started {
+				return nil
+			}
+		case "decline":
+			if err := handleDeclineRequest(s, conn, nickname, args); err != nil {
+				return err
+			}
+		case "tournament", "tournaments":
+			started, err := handleTournamentRequest(s, conn, reader, nickname, args)
+			if err != nil {
+				return err
+			}
+			if started {
+				return nil
+			}
+		case "queue":
+			if err := handleQueueRequest(s, conn); err != nil {
+				return err
+			}
 		case "stats":
 			handleStatsRequest(s, conn, nickname)
+		case "history":
+			if err := handleHistoryRequest(s, conn, nickname, args); err != nil {
+				return err
+			}
+		case "replay":
+			if err := handleReplayRequest(s, conn, reader, args); err != nil {
+				return err
+			}
+		case "export":
+			if err := handleExportRequest(s, conn, args); err != nil {
+				return err
+			}
+		case "import":
+			if err := handleImportRequest(s, conn, reader); err != nil {
+				return err
+			}
+		case "analyze":
+			if err := handleAnalyzeRequest(s, conn, args); err != nil {
+				return err
+			}
 		case "top10":
-			if err := PrintTopPlayers(s.DB, conn); err != nil {
+			if err := PrintTopPlayers(s.DB, conn, s.RatingMinGames); err != nil {
 				if err := trySendMessage(conn, "Error printing top10 players.\r\n"); err != nil {
 					return err
 				}
@@ -179,10 +364,10 @@ func handleBasicCommands(s *models.Server, conn net.Conn, reader *bufio.Reader,
 			}
 		case "quit":
 			conn.Close()
-			handleLogout(s, nickname)
+			handleLogout(s, nickname, conn)
 			return nil
 		default:
-			if err := trySendMessage(conn, "Invalid choice. Please enter 'play', 'stats', 'top10' or 'quit': \r\n"); err != nil {
+			if err := sendEvent(conn, "Invalid choice. Please enter 'play', 'bot', 'rematch', 'host', 'join', 'who', 'challenge', 'accept', 'decline', 'queue', 'tournament', 'stats', 'history', 'replay', 'export', 'import', 'top10', 'analyze' or 'quit': \r\n", errorEvent{Type: "error", Message: fmt.Sprintf("unknown command %q", choice)}); err != nil {
 				return err
 			}
 		}
@@ -196,44 +381,179 @@ func handleStatsRequest(s *models.Server, conn net.Conn, username string) {
 			log.Printf("error sending message: %v", err)
 		}
 		conn.Close()
-		handleLogout(s, username)
+		handleLogout(s, username, conn)
 		return
 	}
 }
 
-func handlePlayerConnection(s *models.Server, conn net.Conn, nickname string) {
-	if err := trySendMessage(conn, "Waiting for an oponent...\r\n"); err != nil {
-		handleLogout(s, nickname)
-		return
+// handlePlayerConnection puts the player in the matchmaking queue and keeps
+// reading their connection until a game takes it over. It reports whether
+// the player left the queue and is back in the lobby.
+func handlePlayerConnection(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string, settings models.GameSettings) bool {
+	if err := trySendMessage(conn, "Waiting for an oponent... Type 'cancel' to leave the queue.\r\n"); err != nil {
+		handleLogout(s, nickname, conn)
+		return false
+	}
+
+	r, err := PlayerRating(s.DB, nickname)
+	if err != nil {
+		r = rating.Default()
 	}
 
 	player := models.Player{
 		IP:       conn.RemoteAddr().String(),
 		Conn:     conn,
 		NickName: nickname,
+		Settings: settings,
+		Rating:   r.Rating,
+		Watch:    newQueueWatch(),
 	}
 
 	s.ConnsChan <- player
+	return awaitMatch(s, conn, reader, nickname, player.Watch)
 }
 
-func handleLogout(s *models.Server, nickname string) {
-	s.ActiveUsersMu.Lock()
-	defer s.ActiveUsersMu.Unlock()
+func requestSettings(conn net.Conn, reader *bufio.Reader) (models.GameSettings, error) {
+	variant, err := requestVariant(conn, reader)
+	if err != nil {
+		return models.GameSettings{}, err
+	}
+
+	settings, err := requestBoard(conn, reader, variant)
+	if err != nil {
+		return models.GameSettings{}, err
+	}
 
-	delete(s.ActiveUsers, nickname)
+	settings.TimeControl, err = requestTimeControl(conn, reader)
+	if err != nil {
+		return models.GameSettings{}, err
+	}
+	return settings, nil
+}
+
+func requestRated(conn net.Conn, reader *bufio.Reader) (bool, error) {
+	for {
+		if err := trySendMessage(conn, "Enter 'rated' or 'casual' (hints allowed, rating unchanged), or press enter for a rated game: "); err != nil {
+			return false, err
+		}
+
+		input, err := tryReadMessage(conn, reader)
+		if err != nil {
+			return false, err
+		}
+
+		switch strings.TrimSpace(strings.ToLower(input)) {
+		case "", "rated":
+			return true, nil
+		case "casual":
+			return false, nil
+		}
+		if err := trySendMessage(conn, "Invalid choice.\r\n"); err != nil {
+			return false, err
+		}
+	}
+}
+
+func requestBoard(conn net.Conn, reader *bufio.Reader, variant engine.Variant) (models.GameSettings, error) {
+	if size, winLength, fixed := variant.Board(); fixed {
+		return models.GameSettings{Variant: variant.Name(), Size: size, WinLength: winLength}, nil
+	}
+
+	for {
+		if err := trySendMessage(conn, "Enter board size and win length (e.g. '15 5'), or press enter for 3x3: "); err != nil {
+			return models.GameSettings{}, err
+		}
+
+		input, err := tryReadMessage(conn, reader)
+		if err != nil {
+			return models.GameSettings{}, err
+		}
+
+		settings, err := parseSettings(input)
+		if err != nil {
+			if err := trySendMessage(conn, fmt.Sprintf("Invalid settings: %s.\r\n", err.Error())); err != nil {
+				return models.GameSettings{}, err
+			}
+			continue
+		}
+		settings.Variant = variant.Name()
+		return settings, nil
+	}
+}
+
+func requestVariant(conn net.Conn, reader *bufio.Reader) (engine.Variant, error) {
+	var list strings.Builder
+	list.WriteString("Variants:\r\n")
+	for _, v := range engine.Variants() {
+		list.WriteString(fmt.Sprintf("  %-12s %s\r\n", v.Name(), v.Description()))
+	}
+	list.WriteString("Choose a variant or press enter for classic: ")
+
+	for {
+		if err := trySendMessage(conn, list.String()); err != nil {
+			return nil, err
+		}
+
+		input, err := tryReadMessage(conn, reader)
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimSpace(strings.ToLower(input))
+		if name == "" {
+			return engine.Classic, nil
+		}
+		if variant, ok := engine.LookupVariant(name); ok {
+			return variant, nil
+		}
+		if err := trySendMessage(conn, fmt.Sprintf("Unknown variant '%s'.\r\n", name)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func parseSettings(input string) (models.GameSettings, error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return models.ClassicSettings(), nil
+	}
+	if len(fields) > 2 {
+		return models.GameSettings{}, fmt.Errorf("expected board size and optional win length")
+	}
+
+	size, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return models.GameSettings{}, fmt.Errorf("board size must be a number")
+	}
+	winLength := min(size, 5)
+	if len(fields) == 2 {
+		winLength, err = strconv.Atoi(fields[1])
+		if err != nil {
+			return models.GameSettings{}, fmt.Errorf("win length must be a number")
+		}
+	}
+
+	if err := engine.ValidateSettings(size, winLength); err != nil {
+		return models.GameSettings{}, err
+	}
+	return models.GameSettings{Variant: engine.ClassicName, Size: size, WinLength: winLength}, nil
 }
 
 func handleSpectatorConnection(s *models.Server, conn net.Conn, reader *bufio.Reader) {
 	s.ActiveGamesMu.Lock()
-	if len(s.Games) == 0 {
+	games := len(s.Games)
+	s.ActiveGamesMu.Unlock()
+	s.TournamentsMu.Lock()
+	tournaments := len(s.Tournaments)
+	s.TournamentsMu.Unlock()
+
+	if games == 0 && tournaments == 0 {
 		if err := trySendMessage(conn, "No games are currently active. Disconnecting.\r\n"); err != nil {
 			log.Printf("error sending message: %v", err)
 		}
 		conn.Close()
-		s.ActiveGamesMu.Unlock()
 		return
 	}
-	s.ActiveGamesMu.Unlock()
 
 	if err := trySendMessage(conn, "Available games:\r\n"); err != nil {
 		return
@@ -241,57 +561,78 @@ func handleSpectatorConnection(s *models.Server, conn net.Conn, reader *bufio.Re
 
 	s.ActiveGamesMu.Lock()
 	for id, game := range s.Games {
-		if err := trySendMessage(conn, fmt.Sprintf("Game ID: %s (Players: %s vs %s)\r\n", id, game.Player1.NickName, game.Player2.NickName)); err != nil {
+		if !listedForSpectators(game) {
+			continue
+		}
+		if err := trySendMessage(conn, fmt.Sprintf("Game ID: %s (Players: %s vs %s, %s)\r\n", id, game.Player1.NickName, game.Player2.NickName, describeSettings(game.Settings))); err != nil {
 			s.ActiveGamesMu.Unlock()
 			return
 		}
 	}
 	s.ActiveGamesMu.Unlock()
 
-	if err := trySendMessage(conn, "Enter the ID of the game you want to spectate: "); err != nil {
-		return
+	if tournaments > 0 {
+		if err := trySendMessage(conn, listTournaments(s)); err != nil {
+			return
+		}
 	}
 
-	gameID, err := tryReadMessage(conn, reader)
-	if err != nil {
-		return
-	}
-	gameID = strings.TrimSpace(gameID)
+	for {
+		if err := trySendMessage(conn, "Enter the ID of the game you want to spectate (or a private room code, or a tournament ID for its standings): "); err != nil {
+			return
+		}
 
-	s.ActiveGamesMu.Lock()
-	game, ok := s.Games[gameID]
-	if !ok {
-		if err := trySendMessage(conn, "Invalid game ID or the game has finished in the meantime. Disconnecting.\n"); err != nil {
-			log.Printf("error sending message: %v", err)
+		gameID, err := tryReadMessage(conn, reader)
+		if err != nil {
+			return
 		}
-		conn.Close()
-		s.ActiveGamesMu.Unlock()
-		return
-	}
+		gameID = strings.TrimSpace(gameID)
+
+		s.TournamentsMu.Lock()
+		t, ok := s.Tournaments[strings.ToUpper(gameID)]
+		if ok {
+			standings := describeTournament(t)
+			s.TournamentsMu.Unlock()
+			if err := trySendMessage(conn, standings); err != nil {
+				return
+			}
+			continue
+		}
+		s.TournamentsMu.Unlock()
 
-	spectator := models.Spectator{Conn: conn}
-	(*game.Spectators)[spectator] = struct{}{}
+		s.ActiveGamesMu.Lock()
+		game, ok := findSpectatableGame(s, gameID)
+		if !ok {
+			if err := trySendMessage(conn, "Invalid game ID or the game has finished in the meantime (finished games can be watched with 'replay <game ID>'). Disconnecting.\n"); err != nil {
+				log.Printf("error sending message: %v", err)
+			}
+			conn.Close()
+			s.ActiveGamesMu.Unlock()
+			return
+		}
 
-	if err := trySendMessage(conn, fmt.Sprintf("You are now spectating game %s.\r\n", gameID)); err != nil {
-		return
-	}
+		addSpectator(game, models.Spectator{Conn: conn})
 
-	s.ActiveGamesMu.Unlock()
+		if err := trySendMessage(conn, fmt.Sprintf("You are now spectating game %s (%s).\r\n", game.ID, game.Variant.Description())); err != nil {
+			s.ActiveGamesMu.Unlock()
+			return
+		}
 
+		s.ActiveGamesMu.Unlock()
+		return
+	}
 }
 
-func HandleConns(s *models.Server) {
-	for {
-		player1 := <-s.ConnsChan
-		player2 := <-s.ConnsChan
-
-		player1.Symbol = "X"
-		player2.Symbol = "O"
-
-		log.Printf("creating game with %s and %s", player1.NickName, player2.NickName)
-
-		go StartGame(player1, player2, s)
+func describeSettings(settings models.GameSettings) string {
+	board := settings.Variant
+	if settings.Variant != engine.UltimateName {
+		board = fmt.Sprintf("%s %dx%d, %d in a row", settings.Variant, settings.Size, settings.Size, settings.WinLength)
+	}
+	mode := "casual"
+	if settings.Rated {
+		mode = "rated"
 	}
+	return fmt.Sprintf("%s, %s, %s", board, describeTimeControl(settings.TimeControl), mode)
 }
 
 func trySendMessage(conn net.Conn, message string) error {
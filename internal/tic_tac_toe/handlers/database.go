@@ -3,11 +3,14 @@ package handlers
 import (
 	"bufio"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"strings"
 	"tic_tac_toe/internal/tic_tac_toe/models"
+	"tic_tac_toe/internal/tic_tac_toe/rating"
+	"time"
 )
 
 func ProcessNickname(db *sql.DB, conn net.Conn, reader *bufio.Reader, nickname string) (bool, error) {
@@ -97,10 +100,20 @@ func VerifyPassword(db *sql.DB, nickname, password string) (bool, error) {
 func MonitorResults(s *models.Server) {
 	for {
 		result := <-s.ResultsChan
+		if result.Player1.Settings.Tournament != "" {
+			recordTournamentResult(s, result)
+		}
 		if result.Error != nil {
 			log.Printf("game %s will not update the database: %v", result.GameID, result.Error)
 			continue
 		}
+		if err := SaveGame(s.DB, result); err != nil {
+			log.Printf("error saving game %s: %v", result.GameID, err)
+		}
+		if result.AgainstBot {
+			log.Printf("game %s was played against a bot, skipping player stats", result.GameID)
+			continue
+		}
 
 		err := UpdatePlayerStats(s.DB, result)
 		if err != nil {
@@ -121,6 +134,12 @@ func UpdatePlayerStats(db *sql.DB, result models.GameResult) error {
 
 	if result.Loser != nil {
 		query := "UPDATE players SET losses = losses + 1, all_games = all_games + 1 WHERE nickname = $1"
+		switch result.Reason {
+		case models.EndTimeout:
+			query = "UPDATE players SET losses = losses + 1, timeout_losses = timeout_losses + 1, all_games = all_games + 1 WHERE nickname = $1"
+		case models.EndResignation:
+			query = "UPDATE players SET losses = losses + 1, resignations = resignations + 1, all_games = all_games + 1 WHERE nickname = $1"
+		}
 		_, err := db.Exec(query, result.Loser.NickName)
 		if err != nil {
 			log.Printf("error updating loser: %v", err)
@@ -137,26 +156,88 @@ func UpdatePlayerStats(db *sql.DB, result models.GameResult) error {
 		}
 	}
 
+	if result.Rated {
+		return UpdateRatings(db, result)
+	}
 	return nil
 }
 
-func PrintPlayerStats(dB *sql.DB, nickname string, conn net.Conn) error {
-	var numberOfGames, wins, losses, draws int
-	query := "SELECT all_games, wins, losses, draws FROM players WHERE nickname=$1"
-	err := dB.QueryRow(query, nickname).Scan(&numberOfGames, &wins, &losses, &draws)
+func UpdateRatings(db *sql.DB, result models.GameResult) error {
+	tx, err := db.Begin()
 	if err != nil {
-		log.Printf("error retrieving player stats: %v", err)
+		log.Printf("error starting rating update: %v", err)
 		return err
 	}
+	defer tx.Rollback()
 
-	var winRate float64
-	if numberOfGames == 0 {
-		winRate = 0
-	} else {
-		winRate = float64(wins) / float64(numberOfGames) * 100
+	nicknames := [2]string{result.Player1.NickName, result.Player2.NickName}
+	var ratings [2]rating.Rating
+	for i, nickname := range nicknames {
+		query := "SELECT rating, rating_deviation, rating_volatility FROM players WHERE nickname = $1 FOR UPDATE"
+		err := tx.QueryRow(query, nickname).Scan(&ratings[i].Rating, &ratings[i].Deviation, &ratings[i].Volatility)
+		if err != nil {
+			log.Printf("error retrieving rating of %s: %v", nickname, err)
+			return err
+		}
 	}
 
-	winRateStr := fmt.Sprintf("%.1f%%", winRate)
+	scores := [2]float64{0.5, 0.5}
+	if result.Winner != nil {
+		scores = [2]float64{0, 1}
+		if result.Winner.NickName == nicknames[0] {
+			scores = [2]float64{1, 0}
+		}
+	}
+
+	for i, nickname := range nicknames {
+		updated := rating.Update(ratings[i], []rating.Result{{Opponent: ratings[1-i], Score: scores[i]}})
+		query := "UPDATE players SET rating = $1, rating_deviation = $2, rating_volatility = $3, rated_games = rated_games + 1 WHERE nickname = $4"
+		_, err := tx.Exec(query, updated.Rating, updated.Deviation, updated.Volatility, nickname)
+		if err != nil {
+			log.Printf("error updating rating of %s: %v", nickname, err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing rating update: %v", err)
+		return err
+	}
+	return nil
+}
+
+func PlayerRating(db *sql.DB, nickname string) (rating.Rating, error) {
+	var r rating.Rating
+	query := "SELECT rating, rating_deviation, rating_volatility FROM players WHERE nickname = $1"
+	err := db.QueryRow(query, nickname).Scan(&r.Rating, &r.Deviation, &r.Volatility)
+	if err != nil {
+		log.Printf("error retrieving rating of %s: %v", nickname, err)
+		return rating.Rating{}, err
+	}
+	return r, nil
+}
+
+func LoadPlayerStats(dB *sql.DB, nickname string) (models.PlayerStats, error) {
+	stats := models.PlayerStats{Nickname: nickname}
+	query := "SELECT all_games, wins, losses, draws, timeout_losses, resignations, rated_games, rating, rating_deviation FROM players WHERE nickname=$1"
+	err := dB.QueryRow(query, nickname).Scan(&stats.Games, &stats.Wins, &stats.Losses, &stats.Draws, &stats.TimeoutLosses, &stats.Resignations, &stats.RatedGames, &stats.Rating.Rating, &stats.Rating.Deviation)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.PlayerStats{}, err
+	}
+	if err != nil {
+		log.Printf("error retrieving player stats: %v", err)
+		return models.PlayerStats{}, err
+	}
+	return stats, nil
+}
+
+func PrintPlayerStats(dB *sql.DB, nickname string, conn net.Conn) error {
+	p, err := LoadPlayerStats(dB, nickname)
+	if err != nil {
+		return err
+	}
+
+	winRateStr := fmt.Sprintf("%.1f%%", p.WinRate())
 
 	stats := fmt.Sprintf(
 		"%s's stats:\r\n"+
@@ -164,13 +245,19 @@ func PrintPlayerStats(dB *sql.DB, nickname string, conn net.Conn) error {
 			"%-12s %-6d\r\n"+
 			"%-12s %-6d\r\n"+
 			"%-12s %-6d\r\n"+
-			"%-12s %-6s\r\n",
+			"%-12s %-6d\r\n"+
+			"%-12s %-6d\r\n"+
+			"%-12s %-6s\r\n"+
+			"%-12s %.0f ±%.0f (%d rated games)\r\n",
 		nickname,
-		"All games:", numberOfGames,
-		"Wins:", wins,
-		"Losses:", losses,
-		"Draws:", draws,
+		"All games:", p.Games,
+		"Wins:", p.Wins,
+		"Losses:", p.Losses,
+		"  on time:", p.TimeoutLosses,
+		"  resigned:", p.Resignations,
+		"Draws:", p.Draws,
 		"Winrate:", winRateStr,
+		"Rating:", p.Rating.Rating, p.Rating.Deviation, p.RatedGames,
 	)
 
 	_, err = conn.Write([]byte(stats))
@@ -182,45 +269,55 @@ func PrintPlayerStats(dB *sql.DB, nickname string, conn net.Conn) error {
 	return nil
 }
 
-func PrintTopPlayers(db *sql.DB, conn net.Conn) error {
+// TopPlayers returns the ten highest rated players with at least minGames
+// rated games.
+func TopPlayers(db *sql.DB, minGames int) ([]models.LeaderboardEntry, error) {
 	query := `
-        SELECT nickname, wins, all_games, 
-        CASE 
-            WHEN all_games = 0 THEN 0
-            ELSE CAST(wins AS FLOAT) / CAST(all_games AS FLOAT)
-        END AS winrate
+        SELECT nickname, rating, rating_deviation, rated_games
         FROM players
-        ORDER BY winrate DESC
+        WHERE rated_games >= $1
+        ORDER BY rating DESC
         LIMIT 10
     `
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, minGames)
 	if err != nil {
 		log.Printf("error retrieving top players: %v", err)
-		return err
+		return nil, err
 	}
 	defer rows.Close()
 
-	var builder strings.Builder
-	builder.WriteString("\r\nTop 10 Players:\r\n")
-	builder.WriteString(fmt.Sprintf("    %-20s %-10s\r\n", "Nickname", "Winrate"))
-
-	for player := 1; rows.Next(); player++ {
-		var nickname string
-		var wins, numberOfGames int
-		var winRate float64
-		err := rows.Scan(&nickname, &wins, &numberOfGames, &winRate)
+	var players []models.LeaderboardEntry
+	for rows.Next() {
+		entry := models.LeaderboardEntry{Rank: len(players) + 1}
+		err := rows.Scan(&entry.Nickname, &entry.Rating.Rating, &entry.Rating.Deviation, &entry.RatedGames)
 		if err != nil {
 			log.Printf("error scanning top player: %v", err)
-			return err
+			return nil, err
 		}
-		builder.WriteString(fmt.Sprintf("%2d. %-20s %6.1f%%\r\n", player, nickname, winRate*100))
+		players = append(players, entry)
 	}
 
 	if err = rows.Err(); err != nil {
 		log.Printf("error iterating over top players: %v", err)
+		return nil, err
+	}
+	return players, nil
+}
+
+func PrintTopPlayers(db *sql.DB, conn net.Conn, minGames int) error {
+	players, err := TopPlayers(db, minGames)
+	if err != nil {
 		return err
 	}
 
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("\r\nTop 10 Players (at least %d rated games):\r\n", minGames))
+	builder.WriteString(fmt.Sprintf("    %-20s %-11s %s\r\n", "Nickname", "Rating", "Games"))
+
+	for _, p := range players {
+		builder.WriteString(fmt.Sprintf("%2d. %-20s %4.0f ±%-4.0f %5d\r\n", p.Rank, p.Nickname, p.Rating.Rating, p.Rating.Deviation, p.RatedGames))
+	}
+
 	_, err = conn.Write([]byte(builder.String()))
 	if err != nil {
 		log.Printf("error writing top players to connection: %v", err)
@@ -229,3 +326,160 @@ func PrintTopPlayers(db *sql.DB, conn net.Conn) error {
 
 	return nil
 }
+
+func SaveGame(db *sql.DB, result models.GameResult) error {
+	tx, err := db.Begin()
+	if err != nil {
+		log.Printf("error starting game insert: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var winner sql.NullString
+	if result.Winner != nil {
+		winner = sql.NullString{String: result.Winner.NickName, Valid: true}
+	}
+
+	settings := result.Settings
+	query := `
+        INSERT INTO games (id, player1, player2, symbol1, symbol2, variant, board_size, win_length,
+            time_base, time_increment, rated, against_bot, started_at, ended_at, winner, end_reason)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+    `
+	_, err = tx.Exec(query, result.GameID, result.Player1.NickName, result.Player2.NickName, result.Player1.Symbol, result.Player2.Symbol,
+		settings.Variant, settings.Size, settings.WinLength, int(settings.TimeControl.Base.Seconds()), int(settings.TimeControl.Increment.Seconds()),
+		result.Rated, result.AgainstBot, result.Started, result.Ended, winner, string(result.Reason))
+	if err != nil {
+		log.Printf("error inserting game %s: %v", result.GameID, err)
+		return err
+	}
+
+	for ply, move := range result.Moves {
+		query := "INSERT INTO moves (game_id, ply, side, move, played_at) VALUES ($1, $2, $3, $4, $5)"
+		_, err := tx.Exec(query, result.GameID, ply+1, int(move.Side), move.Notation, move.At)
+		if err != nil {
+			log.Printf("error inserting move %d of game %s: %v", ply+1, result.GameID, err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("error committing game %s: %v", result.GameID, err)
+		return err
+	}
+	return nil
+}
+
+func RecentGames(db *sql.DB, nickname string, limit int) ([]models.GameRecord, error) {
+	query := `
+        SELECT id, player1, player2, symbol1, symbol2, variant, board_size, win_length, time_base, time_increment,
+            rated, against_bot, started_at, ended_at, COALESCE(winner, ''), end_reason,
+            (SELECT COUNT(*) FROM moves WHERE moves.game_id = games.id)
+        FROM games
+        WHERE player1 = $1 OR player2 = $1
+        ORDER BY ended_at DESC
+        LIMIT $2
+    `
+	rows, err := db.Query(query, nickname, limit)
+	if err != nil {
+		log.Printf("error retrieving games of %s: %v", nickname, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []models.GameRecord
+	for rows.Next() {
+		game, err := scanGameRecord(rows)
+		if err != nil {
+			log.Printf("error scanning game of %s: %v", nickname, err)
+			return nil, err
+		}
+		games = append(games, game)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("error iterating over games of %s: %v", nickname, err)
+		return nil, err
+	}
+	return games, nil
+}
+
+func scanGameRecord(row interface{ Scan(...any) error }) (models.GameRecord, error) {
+	var game models.GameRecord
+	var base, increment int
+	var reason string
+	err := row.Scan(&game.ID, &game.Player1, &game.Player2, &game.Symbol1, &game.Symbol2,
+		&game.Settings.Variant, &game.Settings.Size, &game.Settings.WinLength, &base, &increment,
+		&game.Settings.Rated, &game.AgainstBot, &game.Started, &game.Ended, &game.Winner, &reason, &game.MoveCount)
+	if err != nil {
+		return models.GameRecord{}, err
+	}
+	game.Settings.TimeControl = models.TimeControl{Base: time.Duration(base) * time.Second, Increment: time.Duration(increment) * time.Second}
+	game.Reason = models.EndReason(reason)
+	return game, nil
+}
+
+func PrintGameHistory(db *sql.DB, conn net.Conn, nickname string, limit int) error {
+	games, err := RecentGames(db, nickname, limit)
+	if err != nil {
+		return err
+	}
+
+	var builder strings.Builder
+	if len(games) == 0 {
+		builder.WriteString("You have not finished any games yet.\r\n")
+	} else {
+		builder.WriteString(fmt.Sprintf("\r\n%s's last %d game(s):\r\n", nickname, len(games)))
+		for _, game := range games {
+			builder.WriteString(describeGameRecord(game, nickname))
+		}
+		builder.WriteString("Type 'replay <game ID>' to step through a game.\r\n")
+	}
+
+	_, err = conn.Write([]byte(builder.String()))
+	if err != nil {
+		log.Printf("error writing game history to connection: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func LoadGame(db *sql.DB, id string) (models.GameRecord, error) {
+	query := `
+        SELECT id, player1, player2, symbol1, symbol2, variant, board_size, win_length, time_base, time_increment,
+            rated, against_bot, started_at, ended_at, COALESCE(winner, ''), end_reason,
+            (SELECT COUNT(*) FROM moves WHERE moves.game_id = games.id)
+        FROM games
+        WHERE id = $1
+    `
+	game, err := scanGameRecord(db.QueryRow(query, id))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("error retrieving game %s: %v", id, err)
+		}
+		return models.GameRecord{}, err
+	}
+
+	rows, err := db.Query("SELECT side, move, played_at FROM moves WHERE game_id = $1 ORDER BY ply", id)
+	if err != nil {
+		log.Printf("error retrieving moves of game %s: %v", id, err)
+		return models.GameRecord{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var move models.MoveRecord
+		if err := rows.Scan(&move.Side, &move.Notation, &move.At); err != nil {
+			log.Printf("error scanning move of game %s: %v", id, err)
+			return models.GameRecord{}, err
+		}
+		game.Moves = append(game.Moves, move)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("error iterating over moves of game %s: %v", id, err)
+		return models.GameRecord{}, err
+	}
+	return game, nil
+}
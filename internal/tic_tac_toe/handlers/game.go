@@ -1,45 +1,60 @@
 package handlers
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/bot"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
 	"tic_tac_toe/internal/tic_tac_toe/models"
+	"tic_tac_toe/internal/tic_tac_toe/notation"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-func StartGame(p1 models.Player, p2 models.Player, s *models.Server) {
-	board := [3][3]string{
-		{" ", " ", " "},
-		{" ", " ", " "},
-		{" ", " ", " "},
-	}
-
+func StartGame(p1 models.Player, p2 models.Player, variant engine.Variant, s *models.Server) {
+	claimConn(&p1)
+	claimConn(&p2)
 	gameId := uuid.New().String()
 
-	g := models.Game{
-		ID:            gameId,
-		Player1:       p1,
-		Player2:       p2,
-		OnGoing:       true,
-		CurrentPlayer: &p1,
-		WaitingPlayer: &p2,
-		Board:         &board,
-		Winner:        nil,
-		Loser:         nil,
-		Spectators:    &map[models.Spectator]struct{}{},
+	position, err := variant.NewState(p1.Settings.Size, p1.Settings.WinLength)
+	if err != nil {
+		log.Printf("invalid settings for game with %s and %s: %v", p1.NickName, p2.NickName, err)
+		variant, position = engine.Classic, engine.NewClassicPosition()
+		p1.Settings.Variant, p1.Settings.Size, p1.Settings.WinLength = engine.ClassicName, 3, 3
 	}
 
+	g := models.Game{
+		ID:         gameId,
+		Player1:    p1,
+		Player2:    p2,
+		OnGoing:    true,
+		Variant:    variant,
+		Settings:   p1.Settings,
+		Position:   position,
+		Clock:      newClock(p1.Settings.TimeControl),
+		EndReason:  models.EndNormal,
+		Started:    time.Now(),
+		Winner:     nil,
+		Loser:      nil,
+		Spectators: &map[models.Spectator]struct{}{},
+	}
+	g.Player1.Reconnect = make(chan net.Conn, 1)
+	g.Player2.Reconnect = make(chan net.Conn, 1)
+	g.CurrentPlayer = &g.Player1
+	g.WaitingPlayer = &g.Player2
+	g.Rated = g.Settings.Rated && !isBotGame(&g)
+	g.Settings.Rated = g.Rated
+
 	s.ActiveGamesMu.Lock()
 	s.Games[gameId] = &g
 	s.ActiveGamesMu.Unlock()
 
-	if err := sendMessageToPlayer(g.CurrentPlayer, "The game is starting... you're player 'X'\r\n"); err != nil {
-		handleError(&g, s, err)
-		return
-	}
-	if err := sendMessageToPlayer(g.WaitingPlayer, "The game is starting... you're player '0'\r\n"); err != nil {
+	if err := announceStart(&g); err != nil && !awaitReconnect(&g, s, err) {
 		handleError(&g, s, err)
 		return
 	}
@@ -47,133 +62,209 @@ func StartGame(p1 models.Player, p2 models.Player, s *models.Server) {
 	playGame(&g, s)
 }
 
-func playGame(g *models.Game, s *models.Server) {
-	for g.OnGoing {
-		board := getBoard(g.Board)
-		if err := sendMessageToPlayer(g.CurrentPlayer, board); err != nil {
-			handleError(g, s, err)
-			return
+func announceStart(g *models.Game) error {
+	for _, pair := range [][2]*models.Player{{&g.Player1, &g.Player2}, {&g.Player2, &g.Player1}} {
+		message := fmt.Sprintf("The game is starting (%s)... you're player '%s'\r\n", g.Variant.Description(), pair[0].Symbol)
+		if err := sendEventToPlayer(pair[0], message, newGameStartedEvent(g, pair[0], pair[1])); err != nil {
+			return err
 		}
-		if err := sendMessageToPlayer(g.WaitingPlayer, "Waiting for your oponent's turn...\r\n"); err != nil {
-			handleError(g, s, err)
-			return
+	}
+	for _, pair := range [][2]*models.Player{{&g.Player1, &g.Player2}, {&g.Player2, &g.Player1}} {
+		if pair[1].Bot != nil {
+			if err := sendEventToPlayer(pair[0], fmt.Sprintf("Your opponent is a computer player (%s). This game does not count towards your statistics.\r\n", pair[1].Bot.Name()), nil); err != nil {
+				return err
+			}
 		}
-		sendToSpectators(g, board)
-
-		if err := tryGetMove(g); err != nil {
-			handleError(g, s, err)
-			return
+		if err := sendEventToPlayer(pair[0], "On your turn you can also type 'resign', 'draw' to offer a draw or 'undo' to ask for a takeback.\r\n", nil); err != nil {
+			return err
 		}
-
-		board = getBoard(g.Board)
-		if checkWin(g.Board, g.CurrentPlayer.Symbol) {
-			g.Winner = g.CurrentPlayer
-			g.Loser = g.WaitingPlayer
-			if err := sendFinalBoard(g, board, s); err != nil {
-				return
-			}
-			break
-		} else if isDraw(g.Board) {
-			if err := sendFinalBoard(g, board, s); err != nil {
-				return
+		if !g.Rated {
+			if err := sendEventToPlayer(pair[0], "This is a casual game: type 'hint' or 'analyze' on your turn to ask the solver.\r\n", nil); err != nil {
+				return err
 			}
-			break
+		} else if err := sendEventToPlayer(pair[0], "This is a rated game: the result will change your rating.\r\n", nil); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		if err := sendMessageToPlayer(g.CurrentPlayer, board); err != nil {
+func playGame(g *models.Game, s *models.Server) {
+	for g.OnGoing {
+		err := playTurn(g, s)
+		if err == nil {
+			continue
+		}
+		if endGameEarly(g, err) {
+			sendFinalBoard(g, renderBoard(g.Position)+formatClock(g))
+			break
+		}
+		if !awaitReconnect(g, s, err) {
 			handleError(g, s, err)
 			return
 		}
-		g.CurrentPlayer, g.WaitingPlayer = g.WaitingPlayer, g.CurrentPlayer
 	}
 
 	announceResult(g, s)
 }
 
-func sendFinalBoard(g *models.Game, board string, s *models.Server) error {
-	g.OnGoing = false
-	if err := sendMessageToPlayer(g.CurrentPlayer, board); err != nil {
-		handleError(g, s, err)
+func playTurn(g *models.Game, s *models.Server) error {
+	board := renderBoard(g.Position) + formatClock(g)
+	if err := sendEventToPlayer(g.CurrentPlayer, board, newBoardEvent(g)); err != nil {
 		return err
 	}
-	if err := sendMessageToPlayer(g.WaitingPlayer, board); err != nil {
-		handleError(g, s, err)
+	if err := sendEventToPlayer(g.WaitingPlayer, "Waiting for your oponent's turn...\r\n", nil); err != nil {
 		return err
 	}
-	sendToSpectators(g, board)
-	return nil
-}
+	sendToSpectators(g, board, newBoardEvent(g))
 
-func tryGetMove(g *models.Game) error {
-	var row, col int
-	for {
-		move, err := requestMove(g.CurrentPlayer)
-		if err != nil {
-			return err
-		}
+	if err := tryGetMove(g, s); err != nil {
+		return err
+	}
 
-		row, col, err = validateMove(move, g.Board)
-		if err != nil {
-			if err := sendMessageToPlayer(g.CurrentPlayer, fmt.Sprintf("Invalid move: %s. Try again.\r\n", err.Error())); err != nil {
-				return err
+	// The move is on the board, so hand the turn over before anything else
+	// can fail: a dropped connection must never ask the same player to move
+	// again.
+	mover, opponent := g.CurrentPlayer, g.WaitingPlayer
+	moveMade := newMoveMadeEvent(g, mover)
+	board = renderBoard(g.Position)
+	if outcome := g.Position.Outcome(); outcome.Over() {
+		if winner, ok := outcome.Winner(); ok {
+			g.Winner = playerForSide(g, winner)
+			g.Loser = playerForSide(g, winner.Other())
+		}
+		for _, player := range []*models.Player{mover, opponent} {
+			if err := sendEventToPlayer(player, "", moveMade); err != nil {
+				log.Printf("error sending the last move: %v", err)
 			}
-			continue
 		}
-		updateBoard(g, row, col)
-		break
+		sendEventToSpectators(g, "", moveMade)
+		sendFinalBoard(g, board)
+		return nil
 	}
+	g.CurrentPlayer, g.WaitingPlayer = opponent, mover
 
-	return nil
+	if err := sendEventToPlayer(mover, "", moveMade); err != nil {
+		return err
+	}
+	if err := sendEventToPlayer(opponent, "", moveMade); err != nil {
+		return err
+	}
+	sendEventToSpectators(g, "", moveMade)
+	return sendEventToPlayer(mover, board, newBoardEvent(g))
+}
+
+// sendFinalBoard ends the game and shows the last position to everyone.
+// The result stands even if a player can no longer be reached.
+func sendFinalBoard(g *models.Game, board string) {
+	g.OnGoing = false
+	event := newBoardEvent(g)
+	if err := sendEventToPlayer(g.CurrentPlayer, board, event); err != nil {
+		log.Printf("error sending final board: %v", err)
+	}
+	if err := sendEventToPlayer(g.WaitingPlayer, board, event); err != nil {
+		log.Printf("error sending final board: %v", err)
+	}
+	sendToSpectators(g, board, event)
 }
 
-func updateBoard(g *models.Game, row int, col int) {
-	g.Board[row][col] = g.CurrentPlayer.Symbol
+func endGameEarly(g *models.Game, err error) bool {
+	switch {
+	case errors.Is(err, errFlagFell):
+		g.EndReason = models.EndTimeout
+	case errors.Is(err, errResigned):
+		g.EndReason = models.EndResignation
+	case errors.Is(err, errDrawAgreed):
+		g.EndReason = models.EndAgreement
+		return true
+	default:
+		return false
+	}
+	g.Winner = g.WaitingPlayer
+	g.Loser = g.CurrentPlayer
+	return true
 }
 
-func getBoard(board *[3][3]string) string {
-	var boardStr strings.Builder
+func tryGetMove(g *models.Game, s *models.Server) error {
+	turnStarted := time.Now()
+	if g.DrawOffer == g.WaitingPlayer {
+		if err := sendMessageToPlayer(g.CurrentPlayer, fmt.Sprintf("%s offers a draw. Type 'accept' or 'decline', or make a move to decline.\r\n", g.WaitingPlayer.NickName)); err != nil {
+			return err
+		}
+	}
 
-	boardStr.WriteString("\r\n")
-	boardStr.WriteString("   1   2   3\r\n")
-	rows := []string{"A ", "B ", "C "}
+	for {
+		var limit time.Duration
+		if g.Clock != nil {
+			if limit = timeLeft(g, g.CurrentPlayer, turnStarted); limit <= 0 {
+				return errFlagFell
+			}
+		}
 
-	for i, row := range board {
-		boardStr.WriteString(rows[i])
-		for j, cell := range row {
-			if cell == "" {
-				boardStr.WriteString("   ")
-			} else {
-				boardStr.WriteString(" " + cell + " ")
+		input, err := requestMove(g, limit)
+		if err != nil {
+			return err
+		}
+		if g.Clock != nil && timeLeft(g, g.CurrentPlayer, turnStarted) <= 0 {
+			return errFlagFell
+		}
+
+		handled, err := handleGameCommand(g, s, strings.ToLower(input), &turnStarted)
+		if err != nil {
+			return err
+		}
+		if handled {
+			continue
+		}
+
+		side := g.Position.Turn()
+		move, err := g.Position.ParseMove(input)
+		if err == nil {
+			s.ActiveGamesMu.Lock()
+			if err = g.Position.Apply(move); err == nil {
+				g.Moves = append(g.Moves, models.MoveRecord{Move: move, Side: side, Notation: g.Position.FormatMove(move), At: time.Now()})
 			}
-			if j < 2 {
-				boardStr.WriteString("|")
+			s.ActiveGamesMu.Unlock()
+		}
+		if err != nil {
+			message := fmt.Sprintf("Invalid move: %s. Try again.\r\n", err.Error())
+			if err := sendEventToPlayer(g.CurrentPlayer, message, errorEvent{Type: "error", Message: fmt.Sprintf("invalid move: %s", err.Error())}); err != nil {
+				return err
 			}
+			continue
 		}
-		boardStr.WriteString("\r\n")
 
-		if i < 2 {
-			boardStr.WriteString("  -----------\r\n")
+		if g.DrawOffer == g.WaitingPlayer {
+			if err := declineDraw(g); err != nil {
+				return err
+			}
 		}
+		break
 	}
-	boardStr.WriteString("\r\n")
 
-	return boardStr.String()
+	s.ActiveGamesMu.Lock()
+	chargeClock(g, g.CurrentPlayer, turnStarted)
+	s.ActiveGamesMu.Unlock()
+	return nil
 }
 
-func sendToSpectators(game *models.Game, msg string) {
-	if game.Spectators == nil {
-		return
+func playerForSide(g *models.Game, side engine.Side) *models.Player {
+	if side == engine.First {
+		return &g.Player1
 	}
+	return &g.Player2
+}
 
-	for spectator := range *game.Spectators {
-		_, err := spectator.Conn.Write([]byte(msg))
+func sendToSpectators(game *models.Game, msg string, event any) {
+	for _, spectator := range spectators(game) {
+		err := sendEvent(spectator.Conn, msg, event)
 		if err != nil {
 			spectator.Conn.Close()
 			removeSpectator(game, &spectator)
 			continue
 		}
 		if game.OnGoing {
-			_, err = spectator.Conn.Write([]byte(fmt.Sprintf("%s's turn:\r\n", game.CurrentPlayer.NickName)))
+			err = sendEvent(spectator.Conn, fmt.Sprintf("%s's turn:\r\n", game.CurrentPlayer.NickName), nil)
 			if err != nil {
 				spectator.Conn.Close()
 				removeSpectator(game, &spectator)
@@ -182,160 +273,202 @@ func sendToSpectators(game *models.Game, msg string) {
 	}
 }
 
-func removeSpectator(game *models.Game, spectator *models.Spectator) {
-	if game.Spectators != nil {
-		delete(*game.Spectators, *spectator)
+// sendEventToSpectators is like sendEvent for every spectator of the game.
+func sendEventToSpectators(game *models.Game, text string, event any) {
+	for _, spectator := range spectators(game) {
+		if err := sendEvent(spectator.Conn, text, event); err != nil {
+			spectator.Conn.Close()
+			removeSpectator(game, &spectator)
+		}
 	}
 }
 
-func disconnectSpectators(game *models.Game) {
-	if game.Spectators != nil {
-		for s := range *game.Spectators {
-			s.Conn.Close()
+func notifySpectators(game *models.Game, msg string) {
+	for _, spectator := range spectators(game) {
+		if _, err := spectator.Conn.Write([]byte(msg)); err != nil {
+			spectator.Conn.Close()
+			removeSpectator(game, &spectator)
 		}
 	}
 }
 
-func requestMove(player *models.Player) (string, error) {
-	if err := sendMessageToPlayer(player, "Your move (format: A1, B3, etc.): "); err != nil {
-		return "", err
-	}
+// spectators returns the game's current spectators, so they can be written
+// to without holding the lock while others join.
+func spectators(game *models.Game) []models.Spectator {
+	game.SpectatorsMu.Lock()
+	defer game.SpectatorsMu.Unlock()
 
-	buffer := make([]byte, 1024)
-	n, err := player.Conn.Read(buffer)
-	if err != nil {
-		return "", err
+	if game.Spectators == nil {
+		return nil
 	}
-	return strings.TrimSpace(string(buffer[:n])), nil
+	list := make([]models.Spectator, 0, len(*game.Spectators))
+	for spectator := range *game.Spectators {
+		list = append(list, spectator)
+	}
+	return list
 }
 
-func validateMove(move string, board *[3][3]string) (int, int, error) {
-	if len(move) != 2 {
-		return -1, -1, fmt.Errorf("move must be 2 characters (e.g., A1)")
-	}
-	row := int(move[0] - 'A')
-	col := int(move[1] - '1')
-	if row < 0 || row >= 3 {
-		return -1, -1, fmt.Errorf("row out of bounds")
+func addSpectator(game *models.Game, spectator models.Spectator) {
+	game.SpectatorsMu.Lock()
+	defer game.SpectatorsMu.Unlock()
+
+	if game.Spectators != nil {
+		(*game.Spectators)[spectator] = struct{}{}
 	}
-	if col < 0 || col >= 3 {
-		return -1, -1, fmt.Errorf("column out of bounds")
+}
+
+func removeSpectator(game *models.Game, spectator *models.Spectator) {
+	game.SpectatorsMu.Lock()
+	defer game.SpectatorsMu.Unlock()
+
+	if game.Spectators != nil {
+		delete(*game.Spectators, *spectator)
 	}
-	if board[row][col] != " " {
-		return -1, -1, fmt.Errorf("cell already occupied")
+}
+
+func disconnectSpectators(game *models.Game) {
+	for _, s := range spectators(game) {
+		s.Conn.Close()
 	}
-	return row, col, nil
 }
 
-func checkWin(board *[3][3]string, symbol string) bool {
-	for i := 0; i < 3; i++ {
-		if board[i][0] == symbol && board[i][1] == symbol && board[i][2] == symbol {
-			return true
-		}
-		if board[0][i] == symbol && board[1][i] == symbol && board[2][i] == symbol {
-			return true
+func requestMove(g *models.Game, limit time.Duration) (string, error) {
+	player, position := g.CurrentPlayer, g.Position
+	if player.Bot != nil {
+		if b, ok := player.Bot.(bot.Budgeted); ok && limit > 0 {
+			return position.FormatMove(b.ChooseMoveWithin(position, botThinkBudget(limit))), nil
 		}
+		return position.FormatMove(player.Bot.ChooseMove(position)), nil
 	}
-	if board[0][0] == symbol && board[1][1] == symbol && board[2][2] == symbol {
-		return true
+
+	if err := sendEventToPlayer(player, movePrompt(position), newYourTurnEvent(g, limit)); err != nil {
+		return "", err
 	}
-	if board[0][2] == symbol && board[1][1] == symbol && board[2][0] == symbol {
-		return true
+
+	input, err := readFromPlayer(player, limit)
+	if isTimeout(err) {
+		return "", errFlagFell
 	}
-	return false
+	return input, err
 }
 
-func isDraw(board *[3][3]string) bool {
-	winPatterns := [][][2]int{
-		// Rows
-		{{0, 0}, {0, 1}, {0, 2}},
-		{{1, 0}, {1, 1}, {1, 2}},
-		{{2, 0}, {2, 1}, {2, 2}},
-		// Columns
-		{{0, 0}, {1, 0}, {2, 0}},
-		{{0, 1}, {1, 1}, {2, 1}},
-		{{0, 2}, {1, 2}, {2, 2}},
-		// Diagonals
-		{{0, 0}, {1, 1}, {2, 2}},
-		{{0, 2}, {1, 1}, {2, 0}},
-	}
-
-	canWin := func(player string) bool {
-		for _, pattern := range winPatterns {
-			count := 0
-			empty := 0
-			for _, pos := range pattern {
-				row, col := pos[0], pos[1]
-				if board[row][col] == player {
-					count++
-				} else if board[row][col] == " " {
-					empty++
-				}
-			}
-			if count > 0 && count+empty == 3 {
-				return true
-			}
-		}
-		return false
+func readFromPlayer(player *models.Player, limit time.Duration) (string, error) {
+	if limit > 0 {
+		player.Conn.SetReadDeadline(time.Now().Add(limit))
+		defer player.Conn.SetReadDeadline(time.Time{})
 	}
 
-	if !canWin("X") && !canWin("O") {
-		return true
+	buffer := make([]byte, 1024)
+	n, err := player.Conn.Read(buffer)
+	if err != nil {
+		if isTimeout(err) {
+			return "", err
+		}
+		return "", &disconnectError{player: player, err: fmt.Errorf("failed to read from %s: %w", player.NickName, err)}
 	}
+	return strings.TrimSpace(string(buffer[:n])), nil
+}
 
-	return false
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
 
 func announceResult(g *models.Game, s *models.Server) {
 	resultMessage := ""
 	result := models.GameResult{
-		GameID:  g.ID,
-		Player1: g.Player1,
-		Player2: g.Player2,
-		Winner:  nil,
-		Loser:   nil,
-		Error:   nil,
+		GameID:     g.ID,
+		Player1:    g.Player1,
+		Player2:    g.Player2,
+		Winner:     nil,
+		Loser:      nil,
+		Reason:     g.EndReason,
+		AgainstBot: isBotGame(g),
+		Rated:      g.Rated,
+		Settings:   g.Settings,
+		Moves:      g.Moves,
+		Started:    g.Started,
+		Ended:      time.Now(),
+		Error:      nil,
 	}
 
 	if g.Winner != nil {
 		result.Winner = g.Winner
 		result.Loser = g.Loser
-		resultMessage = fmt.Sprintf("Game Over. %s wins!\r\n", g.Winner.NickName)
-	} else {
-		resultMessage = "Game Over. It's a draw!\r\n"
 	}
+	resultMessage = describeResult(g)
 
-	if err := sendMessageToPlayer(&g.Player1, resultMessage); err != nil {
-		handleError(g, s, err)
-		return
-	}
-	if err := sendMessageToPlayer(&g.Player2, resultMessage); err != nil {
-		handleError(g, s, err)
-		return
+	online := [2]bool{true, true}
+	for i, player := range []*models.Player{&g.Player1, &g.Player2} {
+		reclaimConn(player)
+		if err := sendEventToPlayer(player, resultMessage, newGameOverEvent(g, resultMessage)); err != nil {
+			log.Printf("error sending result: %v", err)
+			online[i] = false
+		}
 	}
 
-	sendToSpectators(g, resultMessage)
+	sendToSpectators(g, resultMessage, newGameOverEvent(g, resultMessage))
 
 	disconnectSpectators(g)
-	g.Player1.Conn.Close()
-	g.Player2.Conn.Close()
 
 	s.ActiveGamesMu.Lock()
 	delete(s.Games, g.ID)
 	s.ActiveGamesMu.Unlock()
 
-	s.ActiveUsersMu.Lock()
-	delete(s.ActiveUsers, g.Player1.NickName)
-	delete(s.ActiveUsers, g.Player2.NickName)
-	s.ActiveUsersMu.Unlock()
+	rememberRematch(s, g)
 
 	s.ResultsChan <- result
+
+	returnToLobby(s, &g.Player1, online[0])
+	returnToLobby(s, &g.Player2, online[1])
+}
+
+// reclaimConn switches to a connection the player resumed with while the
+// game was already ending.
+func reclaimConn(player *models.Player) {
+	select {
+	case conn := <-player.Reconnect:
+		player.Conn.Close()
+		player.Conn = conn
+	default:
+	}
+}
+
+func returnToLobby(s *models.Server, player *models.Player, online bool) {
+	if player.Bot != nil {
+		return
+	}
+	if !online {
+		closePlayerConn(player)
+		endSession(s, player)
+		return
+	}
+	go enterLobby(s, player.Conn, bufio.NewReader(player.Conn), player.NickName)
+}
+
+func describeResult(g *models.Game) string {
+	switch {
+	case g.Winner == nil && g.EndReason == models.EndAgreement:
+		return "Game Over. Draw agreed.\r\n"
+	case g.Winner == nil:
+		return "Game Over. It's a draw!\r\n"
+	case g.EndReason == models.EndTimeout:
+		return fmt.Sprintf("Game Over. %s ran out of time, %s wins!\r\n", g.Loser.NickName, g.Winner.NickName)
+	case g.EndReason == models.EndResignation:
+		return fmt.Sprintf("Game Over. %s resigned, %s wins!\r\n", g.Loser.NickName, g.Winner.NickName)
+	case g.EndReason == models.EndForfeit:
+		return fmt.Sprintf("Game Over. %s did not come back, %s wins by forfeit!\r\n", g.Loser.NickName, g.Winner.NickName)
+	}
+	return fmt.Sprintf("Game Over. %s wins!\r\n", g.Winner.NickName)
 }
 
 func sendMessageToPlayer(player *models.Player, message string) error {
+	if player.Bot != nil {
+		return nil
+	}
 	_, err := player.Conn.Write([]byte(message))
 	if err != nil {
-		return fmt.Errorf("failed to send message to %s: %w", player.NickName, err)
+		return &disconnectError{player: player, err: fmt.Errorf("failed to send message to %s: %w", player.NickName, err)}
 	}
 	return nil
 }
@@ -344,39 +477,50 @@ func handleError(g *models.Game, s *models.Server, err error) {
 	fmt.Printf("Error occurred in game %s: %s\r\n", g.ID, err)
 
 	errorMessage := fmt.Sprintf("Game Over due to an error: %s\r\n", err.Error())
+	gameOver := gameOverEvent{Type: "game_over", GameID: g.ID, Result: notation.Unknown, Reason: "error", Message: strings.TrimSpace(errorMessage)}
 
-	if err := sendMessageToPlayer(g.CurrentPlayer, errorMessage); err != nil {
+	if err := sendEventToPlayer(g.CurrentPlayer, errorMessage, gameOver); err != nil {
 		log.Printf("error sending message to current player: %v", err)
 	}
-	if err := sendMessageToPlayer(g.WaitingPlayer, errorMessage); err != nil {
+	if err := sendEventToPlayer(g.WaitingPlayer, errorMessage, gameOver); err != nil {
 		log.Printf("error sending message to waiting player: %v", err)
 	}
-	for spectator := range *g.Spectators {
-		if _, err := spectator.Conn.Write([]byte(errorMessage)); err != nil {
-			log.Printf("error sending message to spectator: %v", err)
-		}
-	}
+	sendEventToSpectators(g, errorMessage, gameOver)
 
 	disconnectSpectators(g)
-	g.Player1.Conn.Close()
-	g.Player2.Conn.Close()
+	closePlayerConn(&g.Player1)
+	closePlayerConn(&g.Player2)
 
 	s.ActiveGamesMu.Lock()
 	delete(s.Games, g.ID)
 	s.ActiveGamesMu.Unlock()
 
-	s.ActiveUsersMu.Lock()
-	delete(s.ActiveUsers, g.Player1.NickName)
-	delete(s.ActiveUsers, g.Player2.NickName)
-	s.ActiveUsersMu.Unlock()
+	endSession(s, &g.Player1)
+	endSession(s, &g.Player2)
 
 	result := models.GameResult{
-		GameID:  g.ID,
-		Player1: g.Player1,
-		Player2: g.Player2,
-		Winner:  nil,
-		Loser:   nil,
-		Error:   err,
+		GameID:     g.ID,
+		Player1:    g.Player1,
+		Player2:    g.Player2,
+		Winner:     nil,
+		Loser:      nil,
+		AgainstBot: isBotGame(g),
+		Error:      err,
 	}
 	s.ResultsChan <- result
 }
+
+func isBotGame(g *models.Game) bool {
+	return g.Player1.Bot != nil || g.Player2.Bot != nil
+}
+
+func closePlayerConn(player *models.Player) {
+	if player.Conn != nil {
+		player.Conn.Close()
+	}
+	select {
+	case conn := <-player.Reconnect:
+		conn.Close()
+	default:
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+)
+
+// discardConn accepts every write.
+type discardConn struct {
+	net.Conn
+}
+
+func (discardConn) Write(p []byte) (int, error) { return len(p), nil }
+func (discardConn) Close() error                { return nil }
+
+func TestSpectatorsJoinDuringBroadcast(t *testing.T) {
+	g := &models.Game{Spectators: &map[models.Spectator]struct{}{}}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			addSpectator(g, models.Spectator{Conn: &discardConn{}})
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		notifySpectators(g, "Alice offers a draw.\r\n")
+	}
+	wg.Wait()
+
+	if got := len(spectators(g)); got != 200 {
+		t.Errorf("%d spectators, want 200", got)
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+)
+
+const (
+	defaultHistoryLength = 10
+	maxHistoryLength     = 50
+)
+
+func handleHistoryRequest(s *models.Server, conn net.Conn, nickname string, args []string) error {
+	limit := defaultHistoryLength
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > maxHistoryLength {
+			return trySendMessage(conn, fmt.Sprintf("Usage: history [number of games, 1-%d]\r\n", maxHistoryLength))
+		}
+		limit = n
+	}
+
+	if err := PrintGameHistory(s.DB, conn, nickname, limit); err != nil {
+		return trySendMessage(conn, "Failed to retrieve your game history.\r\n")
+	}
+	return nil
+}
+
+// describeGameRecord summarises a stored game from the point of view of
+// one of its players.
+func describeGameRecord(game models.GameRecord, nickname string) string {
+	opponent, symbol := game.Player2, game.Symbol1
+	if nickname == game.Player2 {
+		opponent, symbol = game.Player1, game.Symbol2
+	}
+
+	outcome := "draw"
+	switch game.Winner {
+	case "":
+	case nickname:
+		outcome = "win"
+	default:
+		outcome = "loss"
+	}
+	if game.Reason != models.EndNormal {
+		outcome = fmt.Sprintf("%s (%s)", outcome, game.Reason)
+	}
+
+	return fmt.Sprintf("%s  %s\r\n    vs %s as '%s': %s, %d moves, %s\r\n",
+		game.Ended.Local().Format("2006-01-02 15:04"), game.ID, opponent, symbol, outcome, game.MoveCount, describeSettings(game.Settings))
+}
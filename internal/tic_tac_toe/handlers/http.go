@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+)
+
+func ListenHTTP(s *models.Server) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ws", func(w http.ResponseWriter, r *http.Request) {
+		handleWebSocket(s, w, r)
+	})
+	mux.HandleFunc("GET /api/games", func(w http.ResponseWriter, r *http.Request) {
+		handleAPIGames(s, w, r)
+	})
+	mux.HandleFunc("GET /api/games/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleAPIGame(s, w, r)
+	})
+	mux.HandleFunc("GET /api/games/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		handleGameEvents(s, w, r)
+	})
+	mux.HandleFunc("GET /api/players/{nickname}", func(w http.ResponseWriter, r *http.Request) {
+		handleAPIPlayer(s, w, r)
+	})
+	mux.HandleFunc("GET /api/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+		handleAPILeaderboard(s, w, r)
+	})
+
+	log.Printf("HTTP server is listening on %s", s.HTTPAddr)
+	if err := http.ListenAndServe(s.HTTPAddr, mux); err != nil {
+		log.Printf("HTTP server failed: %v", err)
+	}
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+const (
+	initialRatingRange  = 100.0
+	ratingRangeGrowth   = 10.0 // rating points per second of waiting
+	queueStatusInterval = 15 * time.Second
+	queueWriteTimeout   = time.Second
+	waitHistorySize     = 20
+)
+
+type queuedPlayer struct {
+	player       models.Player
+	since        time.Time
+	notified     time.Time
+	disconnected time.Time // zero while the player is connected
+}
+
+func (q *queuedPlayer) connected() bool {
+	return q.disconnected.IsZero()
+}
+
+// matchQueue holds the players waiting for an opponent, oldest first. It is
+// owned by the HandleConns goroutine.
+type matchQueue struct {
+	players []*queuedPlayer
+	waits   map[models.GameSettings][]time.Duration
+}
+
+func newMatchQueue() *matchQueue {
+	return &matchQueue{waits: make(map[models.GameSettings][]time.Duration)}
+}
+
+// ratingRange is how far apart two ratings may be for players to be paired
+// once one of them has waited this long.
+func ratingRange(waited time.Duration) float64 {
+	return initialRatingRange + ratingRangeGrowth*waited.Seconds()
+}
+
+func (q *matchQueue) add(player models.Player, now time.Time) *queuedPlayer {
+	queued := &queuedPlayer{player: player, since: now}
+	q.players = append(q.players, queued)
+	return queued
+}
+
+func (q *matchQueue) remove(i int) {
+	q.players = append(q.players[:i], q.players[i+1:]...)
+}
+
+// match pairs every connected player it can, oldest first, each with the
+// compatible opponent closest in rating.
+func (q *matchQueue) match(now time.Time) [][2]*queuedPlayer {
+	var pairs [][2]*queuedPlayer
+	for i := 0; i < len(q.players); i++ {
+		a := q.players[i]
+		if !a.connected() {
+			continue
+		}
+		best, bestDiff := -1, 0.0
+		for j := i + 1; j < len(q.players); j++ {
+			b := q.players[j]
+			if !b.connected() || b.player.Settings != a.player.Settings {
+				continue
+			}
+			diff := math.Abs(a.player.Rating - b.player.Rating)
+			if diff > math.Max(ratingRange(now.Sub(a.since)), ratingRange(now.Sub(b.since))) {
+				continue
+			}
+			if best == -1 || diff < bestDiff {
+				best, bestDiff = j, diff
+			}
+		}
+		if best == -1 {
+			continue
+		}
+
+		b := q.players[best]
+		q.recordWait(a, now)
+		q.recordWait(b, now)
+		q.remove(best)
+		q.remove(i)
+		i--
+		pairs = append(pairs, [2]*queuedPlayer{a, b})
+	}
+	return pairs
+}
+
+// expired takes out the connected players who have waited at least
+// timeout.
+func (q *matchQueue) expired(now time.Time, timeout time.Duration) []*queuedPlayer {
+	var expired []*queuedPlayer
+	for i := 0; i < len(q.players); i++ {
+		if !q.players[i].connected() || now.Sub(q.players[i].since) < timeout {
+			continue
+		}
+		expired = append(expired, q.players[i])
+		q.remove(i)
+		i--
+	}
+	return expired
+}
+
+// abandoned takes out the players who have been disconnected for at least
+// grace without resuming their slot.
+func (q *matchQueue) abandoned(now time.Time, grace time.Duration) []*queuedPlayer {
+	var abandoned []*queuedPlayer
+	for i := 0; i < len(q.players); i++ {
+		if q.players[i].connected() || now.Sub(q.players[i].disconnected) < grace {
+			continue
+		}
+		abandoned = append(abandoned, q.players[i])
+		q.remove(i)
+		i--
+	}
+	return abandoned
+}
+
+func (q *matchQueue) reattach(req models.Reattach) bool {
+	for _, queued := range q.players {
+		if queued.player.NickName != req.NickName {
+			continue
+		}
+		queued.player.Conn = req.Conn
+		queued.player.IP = req.Conn.RemoteAddr().String()
+		queued.player.Watch = req.Watch
+		queued.notified = time.Time{}
+		queued.disconnected = time.Time{}
+		return true
+	}
+	return false
+}
+
+func (q *matchQueue) leave(req models.LeaveQueue, now time.Time) bool {
+	for i, queued := range q.players {
+		if queued.player.NickName != req.NickName || queued.player.Conn != req.Conn {
+			continue
+		}
+		if req.Disconnected {
+			queued.disconnected = now
+		} else {
+			q.remove(i)
+		}
+		return true
+	}
+	return false
+}
+
+func (q *matchQueue) recordWait(queued *queuedPlayer, now time.Time) {
+	waits := append(q.waits[queued.player.Settings], now.Sub(queued.since))
+	if len(waits) > waitHistorySize {
+		waits = waits[len(waits)-waitHistorySize:]
+	}
+	q.waits[queued.player.Settings] = waits
+}
+
+// estimate guesses how much longer a player has to wait from how long
+// recent players with the same settings waited. It returns -1 when there
+// is nothing to go by yet.
+func (q *matchQueue) estimate(queued *queuedPlayer, now time.Time) time.Duration {
+	waits := q.waits[queued.player.Settings]
+	if len(waits) == 0 {
+		return -1
+	}
+	var total time.Duration
+	for _, wait := range waits {
+		total += wait
+	}
+	remaining := total/time.Duration(len(waits)) - now.Sub(queued.since)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining.Round(time.Second)
+}
+
+func (q *matchQueue) status(queued *queuedPlayer, now time.Time) models.QueueStatus {
+	position := 1
+	for _, other := range q.players {
+		if other == queued {
+			break
+		}
+		if other.player.Settings == queued.player.Settings {
+			position++
+		}
+	}
+	waited := now.Sub(queued.since)
+	return models.QueueStatus{
+		NickName:      queued.player.NickName,
+		Settings:      queued.player.Settings,
+		Rating:        queued.player.Rating,
+		Position:      position,
+		Waited:        waited.Round(time.Second),
+		RatingRange:   ratingRange(waited),
+		EstimatedWait: q.estimate(queued, now),
+	}
+}
+
+func (q *matchQueue) statuses(now time.Time) []models.QueueStatus {
+	statuses := make([]models.QueueStatus, 0, len(q.players))
+	for _, queued := range q.players {
+		statuses = append(statuses, q.status(queued, now))
+	}
+	return statuses
+}
+
+func HandleConns(s *models.Server) {
+	queue := newMatchQueue()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case player := <-s.ConnsChan:
+			now := time.Now()
+			queue.add(player, now)
+			startMatches(s, queue.match(now))
+			notifyQueue(s, queue, now)
+		case req := <-s.ReattachChan:
+			req.Done <- queue.reattach(req)
+		case req := <-s.LeaveChan:
+			req.Done <- queue.leave(req, time.Now())
+		case reply := <-s.QueueChan:
+			reply <- queue.statuses(time.Now())
+		case now := <-ticker.C:
+			startMatches(s, queue.match(now))
+			if s.BotFillTimeout > 0 {
+				for _, queued := range queue.expired(now, s.BotFillTimeout) {
+					go fillWithBot(s, queued.player)
+				}
+			}
+			for _, queued := range queue.abandoned(now, s.ReconnectGrace) {
+				go handleLogout(s, queued.player.NickName, queued.player.Conn)
+			}
+			notifyQueue(s, queue, now)
+		}
+	}
+}
+
+func startMatches(s *models.Server, pairs [][2]*queuedPlayer) {
+	for _, pair := range pairs {
+		player1, player2 := pair[0].player, pair[1].player
+
+		variant, ok := engine.LookupVariant(player1.Settings.Variant)
+		if !ok {
+			variant = engine.Classic
+		}
+		player1.Symbol = variant.SideName(engine.First)
+		player2.Symbol = variant.SideName(engine.Second)
+
+		log.Printf("creating %s game with %s (%.0f) and %s (%.0f)", describeSettings(player1.Settings), player1.NickName, player1.Rating, player2.NickName, player2.Rating)
+
+		go StartGame(player1, player2, variant, s)
+	}
+}
+
+// notifyQueue tells waiting players where they stand, right after they join
+// and then every queueStatusInterval. Players who can no longer be reached
+// are dropped from the queue.
+func notifyQueue(s *models.Server, queue *matchQueue, now time.Time) {
+	for i := 0; i < len(queue.players); i++ {
+		queued := queue.players[i]
+		if !queued.connected() || now.Sub(queued.notified) < queueStatusInterval {
+			continue
+		}
+		queued.notified = now
+
+		conn := queued.player.Conn
+		conn.SetWriteDeadline(now.Add(queueWriteTimeout))
+		_, err := conn.Write([]byte(describeQueueStatus(queue.status(queued, now))))
+		conn.SetWriteDeadline(time.Time{})
+		if err != nil {
+			log.Printf("dropping %s from the queue: %v", queued.player.NickName, err)
+			conn.Close()
+			queue.remove(i)
+			i--
+			// handleLogout waits for ActiveUsersMu, which a login holds
+			// while it reads the password; the queue must not wait for it.
+			go handleLogout(s, queued.player.NickName, conn)
+		}
+	}
+}
+
+func describeQueueStatus(status models.QueueStatus) string {
+	return fmt.Sprintf("You are #%d in the queue for %s (rating %.0f, looking for opponents within ±%.0f). %s.\r\n",
+		status.Position, describeSettings(status.Settings), status.Rating, status.RatingRange, describeEstimate(status.EstimatedWait))
+}
+
+func describeEstimate(estimate time.Duration) string {
+	switch {
+	case estimate < 0:
+		return "Estimated wait unknown"
+	case estimate == 0:
+		return "An opponent should turn up any moment now"
+	}
+	return fmt.Sprintf("Estimated wait %s", estimate)
+}
+
+func handleQueueRequest(s *models.Server, conn net.Conn) error {
+	reply := make(chan []models.QueueStatus, 1)
+	s.QueueChan <- reply
+	statuses := <-reply
+
+	if len(statuses) == 0 {
+		return trySendMessage(conn, "Nobody is waiting for an opponent.\r\n")
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("\r\n%d player(s) waiting:\r\n", len(statuses)))
+	builder.WriteString(fmt.Sprintf("    %-20s %-7s %-8s %s\r\n", "Nickname", "Rating", "Waiting", "Settings"))
+	for _, status := range statuses {
+		builder.WriteString(fmt.Sprintf("%2d. %-20s %-7.0f %-8s %s\r\n", status.Position, status.NickName, status.Rating, status.Waited, describeSettings(status.Settings)))
+	}
+	return trySendMessage(conn, builder.String())
+}
+
+func newQueueWatch() *models.QueueWatch {
+	return &models.QueueWatch{Handoff: make(chan struct{}), Done: make(chan struct{})}
+}
+
+// awaitMatch reads a queued player's connection for 'cancel' until a game
+// claims it. It reports whether the player left the queue.
+func awaitMatch(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string, watch *models.QueueWatch) bool {
+	defer close(watch.Done)
+	for {
+		select {
+		case <-watch.Handoff:
+			return false
+		default:
+		}
+
+		conn.SetReadDeadline(time.Now().Add(roomPollInterval))
+		input, err := reader.ReadString('\n')
+		conn.SetReadDeadline(time.Time{})
+		if err != nil {
+			if isTimeout(err) {
+				continue
+			}
+			// Keep the slot out of pairing until the player resumes it, so
+			// nobody gets matched with a connection that is gone.
+			conn.Close()
+			detachSession(s, nickname, conn)
+			s.LeaveChan <- models.LeaveQueue{NickName: nickname, Conn: conn, Disconnected: true, Done: make(chan bool, 1)}
+			return false
+		}
+
+		if strings.TrimSpace(strings.ToLower(input)) != "cancel" {
+			if err := trySendMessage(conn, "Still waiting for an oponent. Type 'cancel' to leave the queue.\r\n"); err != nil {
+				return false
+			}
+			continue
+		}
+		done := make(chan bool, 1)
+		s.LeaveChan <- models.LeaveQueue{NickName: nickname, Conn: conn, Done: done}
+		if !<-done {
+			// A game has already claimed the connection.
+			return false
+		}
+		if err := trySendMessage(conn, "You left the queue.\r\n"); err != nil {
+			handleLogout(s, nickname, conn)
+			return false
+		}
+		return true
+	}
+}
+
+// claimConn takes a player coming from the matchmaking queue away from the
+// lobby goroutine still reading their connection.
+func claimConn(player *models.Player) {
+	if player.Watch == nil {
+		return
+	}
+	select {
+	case player.Watch.Handoff <- struct{}{}:
+	case <-player.Watch.Done:
+	}
+	player.Watch = nil
+}
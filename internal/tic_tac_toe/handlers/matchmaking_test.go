@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+var (
+	classicSettings = models.GameSettings{Variant: "classic", Size: 3, WinLength: 3}
+	ratedSettings   = models.GameSettings{Variant: "classic", Size: 3, WinLength: 3, Rated: true}
+	blitzSettings   = models.GameSettings{Variant: "classic", Size: 3, WinLength: 3, TimeControl: models.TimeControl{Base: 3 * time.Minute}}
+)
+
+type waiting struct {
+	nickname string
+	rating   float64
+	settings models.GameSettings
+	waited   time.Duration
+}
+
+// queueOf builds a queue holding the players in order, each of whom has
+// waited as long as given at now.
+func queueOf(now time.Time, players ...waiting) *matchQueue {
+	q := newMatchQueue()
+	for _, p := range players {
+		q.add(models.Player{NickName: p.nickname, Rating: p.rating, Settings: p.settings}, now.Add(-p.waited))
+	}
+	return q
+}
+
+func nicknames(pairs [][2]*queuedPlayer) [][2]string {
+	var names [][2]string
+	for _, pair := range pairs {
+		names = append(names, [2]string{pair[0].player.NickName, pair[1].player.NickName})
+	}
+	return names
+}
+
+func TestRatingRange(t *testing.T) {
+	tests := []struct {
+		waited time.Duration
+		want   float64
+	}{
+		{waited: 0, want: initialRatingRange},
+		{waited: 10 * time.Second, want: initialRatingRange + 10*ratingRangeGrowth},
+		{waited: time.Minute, want: initialRatingRange + 60*ratingRangeGrowth},
+	}
+	for _, tt := range tests {
+		if got := ratingRange(tt.waited); got != tt.want {
+			t.Errorf("ratingRange(%s) = %.0f, want %.0f", tt.waited, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		players []waiting
+		want    [][2]string
+		left    int
+	}{
+		{
+			name:    "close ratings",
+			players: []waiting{{"alice", 1500, classicSettings, 0}, {"bob", 1550, classicSettings, 0}},
+			want:    [][2]string{{"alice", "bob"}},
+		},
+		{
+			name:    "ratings too far apart",
+			players: []waiting{{"alice", 1500, classicSettings, 0}, {"bob", 1650, classicSettings, 0}},
+			left:    2,
+		},
+		{
+			name:    "the window widens with waiting",
+			players: []waiting{{"alice", 1500, classicSettings, 10 * time.Second}, {"bob", 1650, classicSettings, 0}},
+			want:    [][2]string{{"alice", "bob"}},
+		},
+		{
+			name:    "the wider window of the two counts",
+			players: []waiting{{"alice", 1500, classicSettings, 0}, {"bob", 1650, classicSettings, 10 * time.Second}},
+			want:    [][2]string{{"alice", "bob"}},
+		},
+		{
+			name:    "rated and casual do not mix",
+			players: []waiting{{"alice", 1500, classicSettings, 0}, {"bob", 1500, ratedSettings, 0}},
+			left:    2,
+		},
+		{
+			name:    "time controls do not mix",
+			players: []waiting{{"alice", 1500, classicSettings, 0}, {"bob", 1500, blitzSettings, 0}},
+			left:    2,
+		},
+		{
+			name: "closest rating wins",
+			players: []waiting{
+				{"alice", 1500, classicSettings, 0},
+				{"bob", 1580, classicSettings, 0},
+				{"carol", 1510, classicSettings, 0},
+			},
+			want: [][2]string{{"alice", "carol"}},
+			left: 1,
+		},
+		{
+			name: "oldest pairs first",
+			players: []waiting{
+				{"alice", 1500, classicSettings, 20 * time.Second},
+				{"bob", 1550, classicSettings, 10 * time.Second},
+				{"carol", 1540, classicSettings, 0},
+			},
+			want: [][2]string{{"alice", "carol"}},
+			left: 1,
+		},
+		{
+			name: "every compatible pair",
+			players: []waiting{
+				{"alice", 1500, classicSettings, 0},
+				{"bob", 1500, ratedSettings, 0},
+				{"carol", 1500, classicSettings, 0},
+				{"dave", 1500, ratedSettings, 0},
+			},
+			want: [][2]string{{"alice", "carol"}, {"bob", "dave"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			q := queueOf(now, tt.players...)
+			if got := nicknames(q.match(now)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+			if len(q.players) != tt.left {
+				t.Errorf("%d player(s) left in the queue, want %d", len(q.players), tt.left)
+			}
+		})
+	}
+}
+
+func TestMatchSkipsDisconnectedPlayers(t *testing.T) {
+	now := time.Now()
+	q := queueOf(now, waiting{"alice", 1500, classicSettings, 0}, waiting{"bob", 1500, classicSettings, 0})
+
+	if !q.leave(models.LeaveQueue{NickName: "bob", Disconnected: true}, now) {
+		t.Fatal("bob was not found in the queue")
+	}
+	if pairs := q.match(now); len(pairs) != 0 {
+		t.Fatalf("match() paired a disconnected player: %v", nicknames(pairs))
+	}
+	if expired := q.expired(now.Add(time.Hour), time.Minute); len(expired) != 1 || expired[0].player.NickName != "alice" {
+		t.Errorf("expired() = %d player(s), want only alice", len(expired))
+	}
+
+	q = queueOf(now, waiting{"alice", 1500, classicSettings, 0}, waiting{"bob", 1500, classicSettings, 0})
+	q.leave(models.LeaveQueue{NickName: "bob", Disconnected: true}, now)
+	conn, client := net.Pipe()
+	defer conn.Close()
+	defer client.Close()
+	if !q.reattach(models.Reattach{NickName: "bob", Conn: conn}) {
+		t.Fatal("bob could not resume the slot")
+	}
+	if got := nicknames(q.match(now)); !reflect.DeepEqual(got, [][2]string{{"alice", "bob"}}) {
+		t.Errorf("match() after resuming = %v, want alice and bob", got)
+	}
+}
+
+func TestAbandoned(t *testing.T) {
+	now := time.Now()
+	q := queueOf(now, waiting{"alice", 1500, classicSettings, 0}, waiting{"bob", 1500, classicSettings, 0})
+	q.leave(models.LeaveQueue{NickName: "bob", Disconnected: true}, now)
+
+	if abandoned := q.abandoned(now.Add(30*time.Second), time.Minute); len(abandoned) != 0 {
+		t.Errorf("abandoned() dropped %d player(s) within the grace period", len(abandoned))
+	}
+	abandoned := q.abandoned(now.Add(time.Minute), time.Minute)
+	if len(abandoned) != 1 || abandoned[0].player.NickName != "bob" {
+		t.Fatalf("abandoned() = %d player(s), want bob", len(abandoned))
+	}
+	if len(q.players) != 1 {
+		t.Errorf("%d player(s) left in the queue, want 1", len(q.players))
+	}
+}
+
+func TestStatus(t *testing.T) {
+	now := time.Now()
+	q := queueOf(now,
+		waiting{"alice", 1500, classicSettings, 30 * time.Second},
+		waiting{"bob", 1900, ratedSettings, 20 * time.Second},
+		waiting{"carol", 1300, classicSettings, 10 * time.Second},
+	)
+
+	want := []struct {
+		position int
+		waited   time.Duration
+	}{{1, 30 * time.Second}, {1, 20 * time.Second}, {2, 10 * time.Second}}
+	for i, status := range q.statuses(now) {
+		if status.Position != want[i].position {
+			t.Errorf("%s is #%d, want #%d", status.NickName, status.Position, want[i].position)
+		}
+		if status.Waited != want[i].waited {
+			t.Errorf("%s waited %s, want %s", status.NickName, status.Waited, want[i].waited)
+		}
+		if status.RatingRange != ratingRange(want[i].waited) {
+			t.Errorf("%s has a rating range of %.0f, want %.0f", status.NickName, status.RatingRange, ratingRange(want[i].waited))
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		waits  []time.Duration
+		waited time.Duration
+		want   time.Duration
+	}{
+		{name: "no history", want: -1},
+		{name: "average of recent waits", waits: []time.Duration{10 * time.Second, 30 * time.Second}, want: 20 * time.Second},
+		{name: "time already waited counts", waits: []time.Duration{10 * time.Second, 30 * time.Second}, waited: 5 * time.Second, want: 15 * time.Second},
+		{name: "past the average", waits: []time.Duration{10 * time.Second}, waited: time.Minute, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newMatchQueue()
+			for _, wait := range tt.waits {
+				q.recordWait(&queuedPlayer{player: models.Player{Settings: classicSettings}, since: now.Add(-wait)}, now)
+			}
+			// Waits with other settings say nothing about this player.
+			q.recordWait(&queuedPlayer{player: models.Player{Settings: ratedSettings}, since: now.Add(-time.Hour)}, now)
+
+			queued := q.add(models.Player{NickName: "alice", Settings: classicSettings}, now.Add(-tt.waited))
+			if got := q.estimate(queued, now); got != tt.want {
+				t.Errorf("estimate() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordWaitKeepsRecentHistory(t *testing.T) {
+	now := time.Now()
+	q := newMatchQueue()
+	for i := 1; i <= waitHistorySize+5; i++ {
+		q.recordWait(&queuedPlayer{player: models.Player{Settings: classicSettings}, since: now.Add(-time.Duration(i) * time.Second)}, now)
+	}
+	waits := q.waits[classicSettings]
+	if len(waits) != waitHistorySize {
+		t.Fatalf("%d waits kept, want %d", len(waits), waitHistorySize)
+	}
+	if waits[0] != 6*time.Second {
+		t.Errorf("oldest kept wait = %s, want 6s", waits[0])
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"tic_tac_toe/internal/tic_tac_toe/notation"
+)
+
+const maxImportLines = 500
+
+func handleExportRequest(s *models.Server, conn net.Conn, args []string) error {
+	if len(args) != 1 {
+		return trySendMessage(conn, "Usage: export <game ID>\r\n")
+	}
+
+	game, err := LoadGame(s.DB, args[0])
+	if errors.Is(err, sql.ErrNoRows) {
+		return trySendMessage(conn, fmt.Sprintf("There is no finished game with ID %s.\r\n", args[0]))
+	}
+	if err != nil {
+		return trySendMessage(conn, "Failed to load the game.\r\n")
+	}
+
+	text := notation.FromRecord(game).String()
+	return trySendMessage(conn, "\r\n"+strings.ReplaceAll(text, "\n", "\r\n"))
+}
+
+// handleImportRequest reads a game in text notation, checks it against the
+// rules and shows the position it ends in.
+func handleImportRequest(s *models.Server, conn net.Conn, reader *bufio.Reader) error {
+	if err := trySendMessage(conn, "Paste the game in text notation and finish with a line containing only 'end':\r\n"); err != nil {
+		return err
+	}
+
+	var text strings.Builder
+	for lines := 0; ; lines++ {
+		if lines == maxImportLines {
+			return trySendMessage(conn, fmt.Sprintf("The game is longer than %d lines.\r\n", maxImportLines))
+		}
+		line, err := tryReadMessage(conn, reader)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(strings.TrimSpace(line), "end") {
+			break
+		}
+		text.WriteString(line)
+	}
+
+	parsed, err := notation.Parse(text.String())
+	if err == nil {
+		var game *models.Game
+		if game, err = parsed.Rebuild(); err == nil {
+			return trySendMessage(conn, describeImportedGame(parsed, game))
+		}
+	}
+	return trySendMessage(conn, fmt.Sprintf("Invalid game: %s.\r\n", err.Error()))
+}
+
+func describeImportedGame(parsed *notation.Game, g *models.Game) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("\r\n%s ('%s') vs %s ('%s'), %s, %d moves",
+		g.Player1.NickName, g.Player1.Symbol, g.Player2.NickName, g.Player2.Symbol, describeSettings(g.Settings), len(g.Moves)))
+	b.WriteString(renderBoard(g.Position))
+
+	switch {
+	case g.OnGoing:
+		b.WriteString(fmt.Sprintf("The game is not finished: %s ('%s') is to move.\r\n", g.CurrentPlayer.NickName, g.CurrentPlayer.Symbol))
+	case g.Winner != nil:
+		b.WriteString(fmt.Sprintf("Result: %s, %s won", parsed.Result, g.Winner.NickName))
+		if g.EndReason != models.EndNormal {
+			b.WriteString(fmt.Sprintf(" (%s)", g.EndReason))
+		}
+		b.WriteString(".\r\n")
+	default:
+		b.WriteString(fmt.Sprintf("Result: %s.\r\n", parsed.Result))
+	}
+	return b.String()
+}
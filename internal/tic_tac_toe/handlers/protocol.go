@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"tic_tac_toe/internal/tic_tac_toe/bot"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"tic_tac_toe/internal/tic_tac_toe/notation"
+	"time"
+)
+
+const (
+	protocolName    = "json-lines"
+	protocolVersion = 1
+)
+
+// jsonConn speaks the JSON-lines protocol over a client connection. Text
+// written by the lobby and game code reaches the client as "message"
+// events, and typed commands are turned into the lines of text that code
+// reads, so both protocols share one implementation. Events with a typed
+// form are sent with sendEvent instead.
+type jsonConn struct {
+	net.Conn
+	reader  *bufio.Reader
+	partial []byte
+	pending []byte
+	writeMu sync.Mutex
+}
+
+func newJSONConn(conn net.Conn, reader *bufio.Reader) *jsonConn {
+	return &jsonConn{Conn: conn, reader: reader}
+}
+
+func (c *jsonConn) Write(p []byte) (int, error) {
+	if err := c.writeEvent(messageEvent{Type: "message", Text: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *jsonConn) writeEvent(event any) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.Conn.Write(append(data, '\n'))
+	return err
+}
+
+// Read returns one translated line of input at a time. A command that
+// arrives only partly before a read deadline is kept for the next call.
+func (c *jsonConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		line, err := c.reader.ReadBytes('\n')
+		c.partial = append(c.partial, line...)
+		if err != nil {
+			return 0, err
+		}
+		line, c.partial = c.partial, nil
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		lines, err := translateCommand(line)
+		if err != nil {
+			if err := c.writeEvent(errorEvent{Type: "error", Message: err.Error()}); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		for _, l := range lines {
+			c.pending = append(c.pending, l+"\n"...)
+		}
+	}
+
+	end := bytes.IndexByte(c.pending, '\n') + 1
+	n := copy(p, c.pending[:end])
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+type messageEvent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type helloEvent struct {
+	Type     string `json:"type"`
+	Protocol string `json:"protocol"`
+	Version  int    `json:"version"`
+}
+
+type sessionEvent struct {
+	Type     string `json:"type"`
+	Nickname string `json:"nickname"`
+	Token    string `json:"token"`
+}
+
+type lobbyEvent struct {
+	Type     string `json:"type"`
+	Nickname string `json:"nickname"`
+}
+
+type protocolPlayer struct {
+	Nickname string `json:"nickname"`
+	Symbol   string `json:"symbol"`
+	Bot      bool   `json:"bot,omitempty"`
+}
+
+type gameStartedEvent struct {
+	Type        string         `json:"type"`
+	GameID      string         `json:"game_id"`
+	Variant     string         `json:"variant"`
+	Size        int            `json:"size"`
+	WinLength   int            `json:"win_length"`
+	TimeMs      int64          `json:"time_ms,omitempty"`
+	IncrementMs int64          `json:"increment_ms,omitempty"`
+	Rated       bool           `json:"rated"`
+	First       bool           `json:"first"`
+	You         protocolPlayer `json:"you"`
+	Opponent    protocolPlayer `json:"opponent"`
+}
+
+type boardEvent struct {
+	Type      string   `json:"type"`
+	GameID    string   `json:"game_id"`
+	Rows      []string `json:"rows"`
+	ToMove    string   `json:"to_move,omitempty"`
+	NextBoard *int     `json:"next_board,omitempty"`
+	ClockMs   []int64  `json:"clock_ms,omitempty"`
+}
+
+type yourTurnEvent struct {
+	Type        string   `json:"type"`
+	GameID      string   `json:"game_id"`
+	LegalMoves  []string `json:"legal_moves"`
+	TimeLeftMs  int64    `json:"time_left_ms,omitempty"`
+	DrawOffered bool     `json:"draw_offered,omitempty"`
+}
+
+type moveMadeEvent struct {
+	Type   string `json:"type"`
+	GameID string `json:"game_id"`
+	Ply    int    `json:"ply"`
+	Player string `json:"player"`
+	Symbol string `json:"symbol"`
+	Move   string `json:"move"`
+}
+
+type gameOverEvent struct {
+	Type    string `json:"type"`
+	GameID  string `json:"game_id"`
+	Result  string `json:"result"`
+	Winner  string `json:"winner,omitempty"`
+	Loser   string `json:"loser,omitempty"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+type errorEvent struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// protocolCommand is any command a JSON client can send. Only the fields
+// the command type uses need to be set.
+type protocolCommand struct {
+	Type      string   `json:"type"`
+	Nickname  string   `json:"nickname"`
+	Password  string   `json:"password"`
+	Token     string   `json:"token"`
+	GameID    string   `json:"game_id"`
+	Move      string   `json:"move"`
+	Variant   string   `json:"variant"`
+	Size      int      `json:"size"`
+	WinLength int      `json:"win_length"`
+	Minutes   float64  `json:"minutes"`
+	Increment int      `json:"increment"`
+	Casual    bool     `json:"casual"`
+	Level     string   `json:"level"`
+	Text      string   `json:"text"`
+	Args      []string `json:"args"`
+}
+
+// simpleCommands are passed on as the command word followed by its args.
+var simpleCommands = []string{
+	"resign", "draw", "accept", "decline", "undo", "hint", "analyze", "cancel",
+	"rematch", "join", "who", "challenge", "queue", "tournament", "stats", "history",
+	"replay", "export", "top10", "quit",
+}
+
+func translateCommand(line []byte) ([]string, error) {
+	var cmd protocolCommand
+	if err := json.Unmarshal(line, &cmd); err != nil {
+		return nil, fmt.Errorf("invalid command: %s", err.Error())
+	}
+
+	var lines []string
+	switch cmd.Type {
+	case "login":
+		if cmd.Nickname == "" {
+			return nil, fmt.Errorf("login needs a nickname")
+		}
+		lines = []string{"login", cmd.Nickname, cmd.Password}
+	case "resume":
+		lines = []string{"resume " + cmd.Token}
+	case "spectate":
+		lines = []string{"spectate", cmd.GameID}
+	case "move":
+		lines = []string{cmd.Move}
+	case "password":
+		lines = []string{cmd.Password}
+	case "input", "command":
+		lines = []string{cmd.Text}
+	case "play":
+		settings, err := settingsLines(cmd)
+		if err != nil {
+			return nil, err
+		}
+		rated := "rated"
+		if cmd.Casual {
+			rated = "casual"
+		}
+		lines = append(append([]string{"play"}, settings...), rated)
+	case "bot":
+		settings, err := settingsLines(cmd)
+		if err != nil {
+			return nil, err
+		}
+		level := strings.ToLower(cmd.Level)
+		if level == "" {
+			level = bot.HeuristicLevel
+		}
+		if !slices.Contains(bot.Levels(), level) {
+			return nil, fmt.Errorf("unknown bot level %q", cmd.Level)
+		}
+		lines = append(append([]string{"bot"}, settings...), level)
+	default:
+		if !slices.Contains(simpleCommands, cmd.Type) {
+			return nil, fmt.Errorf("unknown command %q", cmd.Type)
+		}
+		lines = []string{strings.Join(append([]string{cmd.Type}, cmd.Args...), " ")}
+	}
+
+	for _, l := range lines {
+		if strings.ContainsAny(l, "\r\n") {
+			return nil, fmt.Errorf("command fields must not contain line breaks")
+		}
+	}
+	return lines, nil
+}
+
+// settingsLines answers the variant, board and clock prompts of
+// requestSettings, checking the answers first so the dialogue never
+// has to ask again.
+func settingsLines(cmd protocolCommand) ([]string, error) {
+	name := strings.ToLower(cmd.Variant)
+	if name == "" {
+		name = engine.ClassicName
+	}
+	variant, ok := engine.LookupVariant(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown variant %q", cmd.Variant)
+	}
+	lines := []string{variant.Name()}
+
+	if _, _, fixed := variant.Board(); !fixed {
+		board := ""
+		if cmd.Size > 0 {
+			board = strconv.Itoa(cmd.Size)
+			if cmd.WinLength > 0 {
+				board += " " + strconv.Itoa(cmd.WinLength)
+			}
+		}
+		if _, err := parseSettings(board); err != nil {
+			return nil, err
+		}
+		lines = append(lines, board)
+	}
+
+	clock := ""
+	if cmd.Minutes > 0 {
+		clock = fmt.Sprintf("%g %d", cmd.Minutes, cmd.Increment)
+	}
+	if _, err := parseTimeControl(clock); err != nil {
+		return nil, err
+	}
+	return append(lines, clock), nil
+}
+
+// eventConn is a connection that receives typed events instead of text.
+type eventConn interface {
+	net.Conn
+	writeEvent(event any) error
+}
+
+func isJSONConn(conn net.Conn) bool {
+	_, ok := conn.(*jsonConn)
+	return ok
+}
+
+// sendEvent sends text to text clients and the typed event to JSON and
+// HTTP clients. Either may be empty, in which case those clients get
+// nothing.
+func sendEvent(conn net.Conn, text string, event any) error {
+	if c, ok := conn.(eventConn); ok {
+		if event == nil {
+			return nil
+		}
+		return c.writeEvent(event)
+	}
+	if text == "" {
+		return nil
+	}
+	return trySendMessage(conn, text)
+}
+
+func sendEventToPlayer(player *models.Player, text string, event any) error {
+	if player.Bot != nil {
+		return nil
+	}
+	if err := sendEvent(player.Conn, text, event); err != nil {
+		return &disconnectError{player: player, err: fmt.Errorf("failed to send message to %s: %w", player.NickName, err)}
+	}
+	return nil
+}
+
+// switchToJSON moves a fresh connection to the JSON-lines protocol.
+func switchToJSON(conn net.Conn, reader *bufio.Reader) (*jsonConn, error) {
+	c := newJSONConn(conn, reader)
+	return c, c.writeEvent(helloEvent{Type: "hello", Protocol: protocolName, Version: protocolVersion})
+}
+
+func newGameStartedEvent(g *models.Game, player, opponent *models.Player) gameStartedEvent {
+	return gameStartedEvent{
+		Type:        "game_started",
+		GameID:      g.ID,
+		Variant:     g.Settings.Variant,
+		Size:        g.Settings.Size,
+		WinLength:   g.Settings.WinLength,
+		TimeMs:      g.Settings.TimeControl.Base.Milliseconds(),
+		IncrementMs: g.Settings.TimeControl.Increment.Milliseconds(),
+		Rated:       g.Rated,
+		First:       player == &g.Player1,
+		You:         protocolPlayer{Nickname: player.NickName, Symbol: player.Symbol, Bot: player.Bot != nil},
+		Opponent:    protocolPlayer{Nickname: opponent.NickName, Symbol: opponent.Symbol, Bot: opponent.Bot != nil},
+	}
+}
+
+func newBoardEvent(g *models.Game) boardEvent {
+	event := boardEvent{Type: "board", GameID: g.ID, Rows: boardRows(g.Position)}
+	if !g.Position.Outcome().Over() {
+		event.ToMove = playerForSide(g, g.Position.Turn()).NickName
+	}
+	if u, ok := g.Position.(*engine.UltimatePosition); ok && u.Next != engine.AnyBoard {
+		next := u.Next + 1
+		event.NextBoard = &next
+	}
+	if g.Clock != nil {
+		event.ClockMs = []int64{g.Clock.Remaining[0].Milliseconds(), g.Clock.Remaining[1].Milliseconds()}
+	}
+	return event
+}
+
+// boardRows writes the board one row per string, with '.' for an empty
+// cell. Ultimate boards are written as the full 9x9 grid.
+func boardRows(state engine.State) []string {
+	var size int
+	var at func(row, col int) string
+	switch pos := state.(type) {
+	case *engine.UltimatePosition:
+		size, at = 9, pos.At
+	case *engine.Position:
+		size, at = pos.Size, pos.At
+	default:
+		return nil
+	}
+
+	rows := make([]string, size)
+	for i := range rows {
+		var row strings.Builder
+		for j := 0; j < size; j++ {
+			cell := at(i, j)
+			if cell == engine.Empty {
+				cell = "."
+			}
+			row.WriteString(cell)
+		}
+		rows[i] = row.String()
+	}
+	return rows
+}
+
+func newYourTurnEvent(g *models.Game, limit time.Duration) yourTurnEvent {
+	moves := g.Position.LegalMoves()
+	event := yourTurnEvent{
+		Type:        "your_turn",
+		GameID:      g.ID,
+		LegalMoves:  make([]string, len(moves)),
+		TimeLeftMs:  limit.Milliseconds(),
+		DrawOffered: g.DrawOffer == g.WaitingPlayer,
+	}
+	for i, move := range moves {
+		event.LegalMoves[i] = g.Position.FormatMove(move)
+	}
+	return event
+}
+
+func newMoveMadeEvent(g *models.Game, player *models.Player) moveMadeEvent {
+	return moveMadeEvent{
+		Type:   "move_made",
+		GameID: g.ID,
+		Ply:    len(g.Moves),
+		Player: player.NickName,
+		Symbol: player.Symbol,
+		Move:   g.Moves[len(g.Moves)-1].Notation,
+	}
+}
+
+func newGameOverEvent(g *models.Game, message string) gameOverEvent {
+	event := gameOverEvent{
+		Type:    "game_over",
+		GameID:  g.ID,
+		Result:  notation.Draw,
+		Reason:  string(g.EndReason),
+		Message: strings.TrimSpace(message),
+	}
+	if g.Winner != nil {
+		event.Winner, event.Loser = g.Winner.NickName, g.Loser.NickName
+		event.Result = notation.FirstWins
+		if g.Winner == &g.Player2 {
+			event.Result = notation.SecondWins
+		}
+	}
+	return event
+}
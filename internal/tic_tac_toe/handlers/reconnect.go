@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+// disconnectError marks a failed read or write on a player's connection,
+// so the game knows whom to wait for.
+type disconnectError struct {
+	player *models.Player
+	err    error
+}
+
+func (e *disconnectError) Error() string {
+	return e.err.Error()
+}
+
+func (e *disconnectError) Unwrap() error {
+	return e.err
+}
+
+// awaitReconnect gives a disconnected player the grace period to log in
+// again and resume the game. It reports false when the game cannot go on,
+// and leaves the game finished by forfeit when the player never returns.
+func awaitReconnect(g *models.Game, s *models.Server, err error) bool {
+	var disconnect *disconnectError
+	if !errors.As(err, &disconnect) || s.ReconnectGrace <= 0 {
+		return false
+	}
+	player := disconnect.player
+	opponent := &g.Player1
+	if player == &g.Player1 {
+		opponent = &g.Player2
+	}
+
+	log.Printf("%s disconnected from game %s, waiting %s for them to return", player.NickName, g.ID, s.ReconnectGrace)
+	player.Conn.Close()
+
+	message := fmt.Sprintf("%s disconnected. Waiting up to %s for them to reconnect...\r\n", player.NickName, s.ReconnectGrace)
+	notifySpectators(g, message)
+	if err := sendMessageToPlayer(opponent, message); err != nil {
+		return false
+	}
+
+	detachSession(s, player.NickName, player.Conn)
+
+	timer := time.NewTimer(s.ReconnectGrace)
+	defer timer.Stop()
+
+	select {
+	case conn := <-player.Reconnect:
+		player.Conn = conn
+		log.Printf("%s reconnected to game %s", player.NickName, g.ID)
+
+		message := fmt.Sprintf("%s reconnected. The game continues.\r\n", player.NickName)
+		notifySpectators(g, message)
+		return sendMessageToPlayer(opponent, message) == nil
+	case <-timer.C:
+		log.Printf("%s did not return to game %s, %s wins by forfeit", player.NickName, g.ID, opponent.NickName)
+		g.Winner = opponent
+		g.Loser = player
+		g.EndReason = models.EndForfeit
+		g.OnGoing = false
+		return true
+	}
+}
+
+// resumeGame hands a new connection to the player's seat in g. The game
+// picks it up as soon as it notices the old connection is gone.
+func resumeGame(g *models.Game, nickname string, conn net.Conn) bool {
+	player := &g.Player1
+	if g.Player2.NickName == nickname {
+		player = &g.Player2
+	}
+
+	select {
+	case player.Reconnect <- conn:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+const rematchTimeout = time.Minute
+
+func rememberRematch(s *models.Server, g *models.Game) {
+	settings := g.Settings
+	settings.Tournament = ""
+
+	s.RematchMu.Lock()
+	defer s.RematchMu.Unlock()
+
+	for i, pair := range [][2]*models.Player{{&g.Player1, &g.Player2}, {&g.Player2, &g.Player1}} {
+		player, opponent := pair[0], pair[1]
+		if player.Bot != nil {
+			continue
+		}
+		s.Rematches[player.NickName] = models.Rematch{
+			Opponent: opponent.NickName,
+			Settings: settings,
+			First:    i == 0,
+			Bot:      opponent.Bot,
+		}
+	}
+}
+
+func forgetRematch(s *models.Server, nicknames ...string) {
+	s.RematchMu.Lock()
+	defer s.RematchMu.Unlock()
+
+	for _, nickname := range nicknames {
+		delete(s.Rematches, nickname)
+	}
+}
+
+// handleRematchRequest replays the player's last game with the colours
+// swapped once the opponent accepts too. Whoever asks second answers the
+// first player's offer, so two requests crossing each other still start
+// one game. It reports whether a game was started, in which case the
+// connection now belongs to that game.
+func handleRematchRequest(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string) (bool, error) {
+	s.RematchMu.Lock()
+	rematch, ok := s.Rematches[nickname]
+	if !ok {
+		s.RematchMu.Unlock()
+		return false, trySendMessage(conn, "You have no previous opponent to play again.\r\n")
+	}
+
+	player := models.Player{
+		IP:       conn.RemoteAddr().String(),
+		Conn:     conn,
+		NickName: nickname,
+		Settings: rematch.Settings,
+	}
+
+	if rematch.Bot != nil {
+		s.RematchMu.Unlock()
+		opponent := models.Player{
+			NickName: rematch.Opponent,
+			Settings: rematch.Settings,
+			Bot:      rematch.Bot,
+		}
+		go startRematch(s, player, opponent, rematch.First)
+		return true, nil
+	}
+
+	if offer, ok := s.RematchOffers[rematch.Opponent]; ok && offer.To == nickname {
+		delete(s.RematchOffers, rematch.Opponent)
+		s.RematchMu.Unlock()
+		offer.Answer <- &player
+		return true, nil
+	}
+
+	offer := &models.RematchOffer{
+		From:   player,
+		To:     rematch.Opponent,
+		Answer: make(chan *models.Player, 1),
+	}
+	s.RematchOffers[nickname] = offer
+	s.RematchMu.Unlock()
+
+	notifyPlayer(s, rematch.Opponent, fmt.Sprintf("\r\n%s wants a rematch. Type 'rematch' within %s to accept.\r\n", nickname, rematchTimeout))
+	if err := trySendMessage(conn, fmt.Sprintf("Waiting up to %s for %s to accept the rematch (type 'cancel' to withdraw it)...\r\n", rematchTimeout, rematch.Opponent)); err != nil {
+		withdrawRematch(s, offer)
+		return false, err
+	}
+
+	withdraw := func() bool {
+		if !withdrawRematch(s, offer) {
+			return false
+		}
+		notifyPlayer(s, rematch.Opponent, fmt.Sprintf("\r\n%s withdrew their rematch request.\r\n", nickname))
+		return true
+	}
+	accepted, err := awaitOpponent(conn, reader, rematchTimeout, offer.Answer, withdraw, fmt.Sprintf("Still waiting for %s to accept the rematch. Type 'cancel' to withdraw it.\r\n", rematch.Opponent))
+	if err != nil || accepted == nil {
+		return false, err
+	}
+
+	forgetRematch(s, nickname, accepted.NickName)
+	go startRematch(s, player, *accepted, rematch.First)
+	return true, nil
+}
+
+// withdrawRematch removes the offer unless the opponent has already
+// answered it, and reports whether it did.
+func withdrawRematch(s *models.Server, offer *models.RematchOffer) bool {
+	s.RematchMu.Lock()
+	defer s.RematchMu.Unlock()
+
+	if s.RematchOffers[offer.From.NickName] != offer {
+		return false
+	}
+	delete(s.RematchOffers, offer.From.NickName)
+	return true
+}
+
+func startRematch(s *models.Server, player models.Player, opponent models.Player, wasFirst bool) {
+	variant, ok := engine.LookupVariant(player.Settings.Variant)
+	if !ok {
+		variant = engine.Classic
+	}
+
+	player1, player2 := opponent, player
+	if !wasFirst {
+		player1, player2 = player, opponent
+	}
+	player1.Symbol = variant.SideName(engine.First)
+	player2.Symbol = variant.SideName(engine.Second)
+
+	log.Printf("creating %s rematch with %s and %s", describeSettings(player.Settings), player1.NickName, player2.NickName)
+
+	StartGame(player1, player2, variant, s)
+}
+
+func notifyPlayer(s *models.Server, nickname string, message string) {
+	s.ActiveUsersMu.Lock()
+	var conn net.Conn
+	if session, ok := s.ActiveUsers[nickname]; ok {
+		conn = session.Conn
+	}
+	s.ActiveUsersMu.Unlock()
+
+	if conn == nil {
+		return
+	}
+	if _, err := conn.Write([]byte(message)); err != nil {
+		log.Printf("error notifying %s: %v", nickname, err)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+)
+
+const replayPrompt = "Enter 'next' (or just press enter), 'prev', 'first', 'last', a move number or 'quit': "
+
+// handleReplayRequest steps through a stored game one move at a time until
+// the viewer quits.
+func handleReplayRequest(s *models.Server, conn net.Conn, reader *bufio.Reader, args []string) error {
+	if len(args) != 1 {
+		return trySendMessage(conn, "Usage: replay <game ID>\r\n")
+	}
+
+	game, err := LoadGame(s.DB, args[0])
+	if errors.Is(err, sql.ErrNoRows) {
+		return trySendMessage(conn, fmt.Sprintf("There is no finished game with ID %s.\r\n", args[0]))
+	}
+	if err != nil {
+		return trySendMessage(conn, "Failed to load the game.\r\n")
+	}
+
+	positions, err := replayPositions(game)
+	if err != nil {
+		return trySendMessage(conn, fmt.Sprintf("Cannot replay game %s: %s.\r\n", game.ID, err.Error()))
+	}
+
+	header := fmt.Sprintf("\r\nReplay of game %s, played %s\r\n%s ('%s') vs %s ('%s'), %s\r\nResult: %s, %d moves\r\n",
+		game.ID, game.Started.Local().Format("2006-01-02 15:04"), game.Player1, game.Symbol1, game.Player2, game.Symbol2,
+		describeSettings(game.Settings), describeRecordResult(game), len(game.Moves))
+	if err := trySendMessage(conn, header); err != nil {
+		return err
+	}
+
+	ply := 0
+	for {
+		if err := trySendMessage(conn, describeReplayStep(game, positions, ply)+replayPrompt); err != nil {
+			return err
+		}
+
+		input, err := tryReadMessage(conn, reader)
+		if err != nil {
+			return err
+		}
+
+		switch command := strings.ToLower(strings.TrimSpace(input)); command {
+		case "", "next", "n":
+			if ply < len(game.Moves) {
+				ply++
+			}
+		case "prev", "p":
+			if ply > 0 {
+				ply--
+			}
+		case "first":
+			ply = 0
+		case "last":
+			ply = len(game.Moves)
+		case "quit", "q":
+			return nil
+		default:
+			n, err := strconv.Atoi(command)
+			if err != nil || n < 0 || n > len(game.Moves) {
+				if err := trySendMessage(conn, fmt.Sprintf("Invalid choice. Move numbers go from 0 to %d.\r\n", len(game.Moves))); err != nil {
+					return err
+				}
+				continue
+			}
+			ply = n
+		}
+	}
+}
+
+// replayPositions rebuilds every position of a stored game, starting with
+// the empty board.
+func replayPositions(game models.GameRecord) ([]engine.State, error) {
+	variant, ok := engine.LookupVariant(game.Settings.Variant)
+	if !ok {
+		return nil, fmt.Errorf("unknown variant %s", game.Settings.Variant)
+	}
+	position, err := variant.NewState(game.Settings.Size, game.Settings.WinLength)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := []engine.State{position.Clone()}
+	for i, record := range game.Moves {
+		move, err := position.ParseMove(record.Notation)
+		if err == nil {
+			err = position.Apply(move)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("move %d (%s): %w", i+1, record.Notation, err)
+		}
+		positions = append(positions, position.Clone())
+	}
+	return positions, nil
+}
+
+func describeReplayStep(game models.GameRecord, positions []engine.State, ply int) string {
+	if ply == 0 {
+		return fmt.Sprintf("\r\nStart of the game (move 0 of %d):", len(game.Moves)) + renderBoard(positions[0])
+	}
+
+	record := game.Moves[ply-1]
+	player, symbol := game.Player1, game.Symbol1
+	if record.Side == engine.Second {
+		player, symbol = game.Player2, game.Symbol2
+	}
+	elapsed := record.At.Sub(game.Started)
+	if ply > 1 {
+		elapsed = record.At.Sub(game.Moves[ply-2].At)
+	}
+
+	step := fmt.Sprintf("\r\nMove %d of %d: %s ('%s') played %s after %s", ply, len(game.Moves), player, symbol, record.Notation, formatDuration(elapsed))
+	step += renderBoard(positions[ply])
+	if ply == len(game.Moves) {
+		step += fmt.Sprintf("End of the game: %s.\r\n", describeRecordResult(game))
+	}
+	return step
+}
+
+func describeRecordResult(game models.GameRecord) string {
+	if game.Winner == "" {
+		if game.Reason == models.EndNormal {
+			return "draw"
+		}
+		return fmt.Sprintf("draw (%s)", game.Reason)
+	}
+	if game.Reason == models.EndNormal {
+		return fmt.Sprintf("%s won", game.Winner)
+	}
+	return fmt.Sprintf("%s won (%s)", game.Winner, game.Reason)
+}
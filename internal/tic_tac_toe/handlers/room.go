@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+const (
+	roomCodeLength   = 6
+	roomCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	roomTimeout      = 10 * time.Minute
+	roomPollInterval = 500 * time.Millisecond
+)
+
+// handleHostRequest opens a private room and waits for a guest to join it.
+// It reports whether a game was started, in which case the connection now
+// belongs to that game.
+func handleHostRequest(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string) (bool, error) {
+	settings, err := requestSettings(conn, reader)
+	if err != nil {
+		return false, err
+	}
+	settings.Rated, err = requestRated(conn, reader)
+	if err != nil {
+		return false, err
+	}
+	settings.Visibility, err = requestVisibility(conn, reader)
+	if err != nil {
+		return false, err
+	}
+
+	room := &models.Room{
+		Host: models.Player{
+			IP:       conn.RemoteAddr().String(),
+			Conn:     conn,
+			NickName: nickname,
+		},
+		Created: time.Now(),
+		Join:    make(chan *models.Player, 1),
+	}
+
+	s.RoomsMu.Lock()
+	for room.Code == "" || s.Rooms[room.Code] != nil {
+		room.Code = newRoomCode()
+	}
+	settings.Room = room.Code
+	room.Settings = settings
+	room.Host.Settings = settings
+	s.Rooms[room.Code] = room
+	s.RoomsMu.Unlock()
+
+	log.Printf("%s opened private room %s (%s)", nickname, room.Code, describeSettings(settings))
+
+	if err := trySendMessage(conn, fmt.Sprintf("Your room code is %s. Your friend can type 'join %s' to play you.\r\nWaiting for them to join (type 'cancel' to close the room)...\r\n", room.Code, room.Code)); err != nil {
+		closeRoom(s, room)
+		return false, err
+	}
+
+	withdraw := func() bool { return closeRoom(s, room) }
+	guest, err := awaitOpponent(conn, reader, roomTimeout, room.Join, withdraw, "Still waiting for your friend. Type 'cancel' to close the room.\r\n")
+	if err != nil || guest == nil {
+		return false, err
+	}
+
+	go startDirectGame(s, room.Host, *guest)
+	return true, nil
+}
+
+// awaitOpponent waits for an answer while polling the connection for
+// 'cancel'. When the wait is cancelled or times out, withdraw takes the offer
+// back; if someone has claimed it in the meantime their answer is returned
+// after all. A nil player means nobody is coming.
+func awaitOpponent(conn net.Conn, reader *bufio.Reader, timeout time.Duration, answers <-chan *models.Player, withdraw func() bool, reminder string) (*models.Player, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		select {
+		case opponent := <-answers:
+			return opponent, nil
+		default:
+		}
+
+		if time.Now().After(deadline) {
+			if !withdraw() {
+				return <-answers, nil
+			}
+			return nil, trySendMessage(conn, "Nobody answered in time.\r\n")
+		}
+
+		conn.SetReadDeadline(time.Now().Add(roomPollInterval))
+		input, err := reader.ReadString('\n')
+		conn.SetReadDeadline(time.Time{})
+		if err != nil {
+			if isTimeout(err) {
+				continue
+			}
+			conn.Close()
+			if !withdraw() {
+				return <-answers, nil
+			}
+			return nil, err
+		}
+
+		if strings.TrimSpace(strings.ToLower(input)) != "cancel" {
+			if err := trySendMessage(conn, reminder); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !withdraw() {
+			return <-answers, nil
+		}
+		return nil, trySendMessage(conn, "Cancelled.\r\n")
+	}
+}
+
+// closeRoom removes the room unless a guest has already claimed it, and
+// reports whether it did.
+func closeRoom(s *models.Server, room *models.Room) bool {
+	s.RoomsMu.Lock()
+	defer s.RoomsMu.Unlock()
+
+	if s.Rooms[room.Code] != room {
+		return false
+	}
+	delete(s.Rooms, room.Code)
+	log.Printf("private room %s was closed", room.Code)
+	return true
+}
+
+func handleJoinRequest(s *models.Server, conn net.Conn, nickname string, args []string) (bool, error) {
+	if len(args) != 1 {
+		return false, trySendMessage(conn, "Usage: join <code>\r\n")
+	}
+	code := strings.ToUpper(args[0])
+
+	s.RoomsMu.Lock()
+	room, ok := s.Rooms[code]
+	if ok && room.Host.NickName != nickname {
+		delete(s.Rooms, code)
+	}
+	s.RoomsMu.Unlock()
+
+	if !ok {
+		return false, trySendMessage(conn, fmt.Sprintf("There is no open room with code %s.\r\n", code))
+	}
+	if room.Host.NickName == nickname {
+		return false, trySendMessage(conn, "You cannot join your own room.\r\n")
+	}
+
+	room.Join <- &models.Player{
+		IP:       conn.RemoteAddr().String(),
+		Conn:     conn,
+		NickName: nickname,
+		Settings: room.Settings,
+	}
+	return true, nil
+}
+
+func startDirectGame(s *models.Server, host models.Player, guest models.Player) {
+	variant, ok := engine.LookupVariant(host.Settings.Variant)
+	if !ok {
+		variant = engine.Classic
+	}
+
+	player1, player2 := host, guest
+	if rand.Intn(2) == 1 {
+		player1, player2 = guest, host
+	}
+	player1.Symbol = variant.SideName(engine.First)
+	player2.Symbol = variant.SideName(engine.Second)
+
+	log.Printf("creating direct %s game with %s and %s", describeSettings(host.Settings), player1.NickName, player2.NickName)
+
+	StartGame(player1, player2, variant, s)
+}
+
+func requestVisibility(conn net.Conn, reader *bufio.Reader) (string, error) {
+	for {
+		if err := trySendMessage(conn, "Who may spectate? Enter 'public' (listed for everyone), 'code' (only with the room code) or 'hidden' (nobody), or press enter for public: "); err != nil {
+			return "", err
+		}
+
+		input, err := tryReadMessage(conn, reader)
+		if err != nil {
+			return "", err
+		}
+
+		switch visibility := strings.TrimSpace(strings.ToLower(input)); visibility {
+		case "":
+			return models.VisibilityPublic, nil
+		case models.VisibilityPublic, models.VisibilityCode, models.VisibilityHidden:
+			return visibility, nil
+		}
+		if err := trySendMessage(conn, "Invalid choice.\r\n"); err != nil {
+			return "", err
+		}
+	}
+}
+
+func newRoomCode() string {
+	code := make([]byte, roomCodeLength)
+	for i := range code {
+		code[i] = roomCodeAlphabet[rand.Intn(len(roomCodeAlphabet))]
+	}
+	return string(code)
+}
+
+// listedForSpectators reports whether the game shows up in the spectator
+// list. Games in private rooms may be hidden or reachable only by code.
+func listedForSpectators(g *models.Game) bool {
+	return g.Settings.Visibility != models.VisibilityHidden && g.Settings.Visibility != models.VisibilityCode
+}
+
+// findRoomGame looks up the running game of a private room that allows
+// spectators with its code. The caller must hold s.ActiveGamesMu.
+func findRoomGame(s *models.Server, code string) (*models.Game, bool) {
+	code = strings.ToUpper(code)
+	for _, g := range s.Games {
+		if g.Settings.Visibility == models.VisibilityCode && g.Settings.Room == code {
+			return g, true
+		}
+	}
+	return nil, false
+}
+
+// findSpectatableGame looks up a running game by ID, or by room code for
+// private rooms that allow spectators. The caller must hold
+// s.ActiveGamesMu.
+func findSpectatableGame(s *models.Server, id string) (*models.Game, bool) {
+	if g, ok := s.Games[id]; ok && listedForSpectators(g) {
+		return g, true
+	}
+	return findRoomGame(s, id)
+}
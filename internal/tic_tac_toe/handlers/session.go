@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+
+	"github.com/google/uuid"
+)
+
+// newSession registers a fresh session for nickname. The caller must hold
+// s.ActiveUsersMu.
+func newSession(s *models.Server, nickname string, conn net.Conn) *models.Session {
+	session := &models.Session{
+		Token:    uuid.New().String(),
+		NickName: nickname,
+		Conn:     conn,
+	}
+	s.ActiveUsers[nickname] = session
+	s.Sessions[session.Token] = session
+	return session
+}
+
+// sessionInUse reports whether a second login must be turned away because
+// the session still has a live connection. The caller must hold
+// s.ActiveUsersMu.
+func sessionInUse(s *models.Server, session *models.Session) bool {
+	return session != nil && session.Conn != nil && s.SessionPolicy != models.SessionTakeover
+}
+
+// attachSession moves the session to conn and closes the connection it
+// replaces, if any. The caller must hold s.ActiveUsersMu.
+func attachSession(session *models.Session, conn net.Conn) {
+	old := session.Conn
+	session.Conn = conn
+	if old != nil {
+		log.Printf("%s took over the session of %s", conn.RemoteAddr(), session.NickName)
+		old.Close()
+	}
+}
+
+// detachSession marks the session as disconnected, as long as conn is still
+// the one it is attached to.
+func detachSession(s *models.Server, nickname string, conn net.Conn) {
+	s.ActiveUsersMu.Lock()
+	defer s.ActiveUsersMu.Unlock()
+
+	if session, ok := s.ActiveUsers[nickname]; ok && session.Conn == conn {
+		session.Conn = nil
+	}
+}
+
+// handleLogout ends the session of nickname, unless it has meanwhile been
+// taken over by another connection.
+func handleLogout(s *models.Server, nickname string, conn net.Conn) {
+	s.ActiveUsersMu.Lock()
+	defer s.ActiveUsersMu.Unlock()
+
+	session, ok := s.ActiveUsers[nickname]
+	if !ok || (session.Conn != nil && session.Conn != conn) {
+		return
+	}
+	delete(s.ActiveUsers, nickname)
+	delete(s.Sessions, session.Token)
+	forgetRematch(s, nickname)
+}
+
+func endSession(s *models.Server, player *models.Player) {
+	if player.Bot != nil {
+		return
+	}
+	handleLogout(s, player.NickName, player.Conn)
+}
+
+// handleResume attaches conn to the session holding token. The token proves
+// ownership, so whatever connection the session still has is closed, even
+// under the reject policy: it is most likely one that died without the
+// server noticing yet.
+func handleResume(s *models.Server, conn net.Conn, reader *bufio.Reader, token string) {
+	s.ActiveUsersMu.Lock()
+	session, ok := s.Sessions[token]
+	if !ok {
+		s.ActiveUsersMu.Unlock()
+		if err := trySendMessage(conn, "Unknown or expired session token. Disconnecting.\r\n"); err != nil {
+			log.Printf("error sending message: %v", err)
+		}
+		conn.Close()
+		return
+	}
+	attachSession(session, conn)
+	s.ActiveUsersMu.Unlock()
+
+	log.Printf("%s resumed the session of %s", conn.RemoteAddr(), session.NickName)
+
+	resumeSession(s, conn, reader, session.NickName)
+}
+
+// resumeSession puts a newly attached connection back where the session
+// left off: in its game, in the matchmaking queue or in the lobby.
+func resumeSession(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string) {
+	if err := trySendMessage(conn, fmt.Sprintf("Welcome back, %s.\r\n", nickname)); err != nil {
+		handleLogout(s, nickname, conn)
+		return
+	}
+
+	if g := findGame(s, nickname); g != nil {
+		if err := trySendMessage(conn, "Rejoining your game...\r\n"); err != nil {
+			handleLogout(s, nickname, conn)
+			return
+		}
+		if resumeGame(g, nickname, conn) {
+			return
+		}
+	}
+
+	done := make(chan bool, 1)
+	watch := newQueueWatch()
+	s.ReattachChan <- models.Reattach{NickName: nickname, Conn: conn, Watch: watch, Done: done}
+	if <-done {
+		if err := trySendMessage(conn, "You are back in the queue. Waiting for an oponent... Type 'cancel' to leave the queue.\r\n"); err != nil {
+			log.Printf("error sending message: %v", err)
+		}
+		if !awaitMatch(s, conn, reader, nickname, watch) {
+			return
+		}
+	}
+
+	enterLobby(s, conn, reader, nickname)
+}
+
+func findGame(s *models.Server, nickname string) *models.Game {
+	s.ActiveGamesMu.Lock()
+	defer s.ActiveGamesMu.Unlock()
+
+	for _, g := range s.Games {
+		if !g.OnGoing {
+			continue
+		}
+		if (g.Player1.Bot == nil && g.Player1.NickName == nickname) || (g.Player2.Bot == nil && g.Player2.NickName == nickname) {
+			return g
+		}
+	}
+	return nil
+}
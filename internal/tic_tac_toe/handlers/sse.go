@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+const sseWriteTimeout = time.Second
+
+// sseConn streams events to an HTTP spectator as Server-Sent Events. Each
+// event is one "data" line holding the same JSON the JSON-lines protocol
+// uses, and text written to the connection arrives as "message" events.
+type sseConn struct {
+	net.Conn
+	writeMu sync.Mutex
+}
+
+type snapshotEvent struct {
+	Type string `json:"type"`
+	apiGameBoard
+}
+
+// handleGameEvents adds the client as a spectator of a running game. It
+// gets a snapshot of the game first and then the same events as every
+// other spectator until the game ends and the stream is closed. Nothing is
+// written to the client while s.ActiveGamesMu is held, so a slow client
+// cannot hold up the games.
+func handleGameEvents(s *models.Server, w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.ActiveGamesMu.Lock()
+	_, ok := findSpectatableGame(s, id)
+	s.ActiveGamesMu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "game not found"})
+		return
+	}
+
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		log.Printf("event stream hijack failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: "event streams not supported"})
+		return
+	}
+
+	response := "HTTP/1.1 200 OK\r\n" +
+		"Content-Type: text/event-stream\r\n" +
+		"Cache-Control: no-cache\r\n" +
+		"Access-Control-Allow-Origin: *\r\n" +
+		"Connection: close\r\n\r\n"
+	conn.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+	_, err = conn.Write([]byte(response))
+	conn.SetWriteDeadline(time.Time{})
+	if err != nil {
+		log.Printf("event stream to %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+
+	// The game may have ended while the headers were written. Holding
+	// writeMu from before the client joins until the snapshot is out keeps
+	// live events from overtaking the snapshot.
+	client := &sseConn{Conn: conn}
+	s.ActiveGamesMu.Lock()
+	g, ok := findSpectatableGame(s, id)
+	if !ok {
+		s.ActiveGamesMu.Unlock()
+		conn.Close()
+		return
+	}
+	snapshot := snapshotEvent{Type: "snapshot", apiGameBoard: newAPIGameBoard(g)}
+	client.writeMu.Lock()
+	spectator := models.Spectator{Conn: client}
+	addSpectator(g, spectator)
+	s.ActiveGamesMu.Unlock()
+
+	err = client.writeEventLocked(snapshot)
+	client.writeMu.Unlock()
+	if err != nil {
+		log.Printf("event stream to %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		removeSpectator(g, &spectator)
+		return
+	}
+
+	log.Printf("new event stream spectator of game %s from %s", g.ID, conn.RemoteAddr())
+}
+
+func (c *sseConn) Write(p []byte) (int, error) {
+	if err := c.writeEvent(messageEvent{Type: "message", Text: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *sseConn) writeEvent(event any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.writeEventLocked(event)
+}
+
+// writeEventLocked writes one event, giving up after sseWriteTimeout so a
+// client that stopped reading fails instead of stalling the game that
+// broadcasts to it. The caller must hold c.writeMu.
+func (c *sseConn) writeEventLocked(event any) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	c.Conn.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+	defer c.Conn.SetWriteDeadline(time.Time{})
+	_, err = fmt.Fprintf(c.Conn, "data: %s\n\n", data)
+	return err
+}
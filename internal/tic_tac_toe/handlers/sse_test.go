@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSSEWriteGivesUpOnStalledClient(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	stream := &sseConn{Conn: server}
+
+	done := make(chan error, 1)
+	go func() { done <- stream.writeEvent(messageEvent{Type: "message", Text: "X plays B2"}) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("writeEvent succeeded although the client never read")
+		}
+	case <-time.After(5 * sseWriteTimeout):
+		t.Fatal("writeEvent blocked on a client that stopped reading")
+	}
+}
@@ -0,0 +1,578 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"tic_tac_toe/internal/tic_tac_toe/tournament"
+	"time"
+)
+
+const tournamentUsage = "Usage: tournament [list | show <id> | join <id> | leave <id> | play | create <format> <minutes> | start <id>]\r\n"
+
+// handleTournamentRequest runs the 'tournament' lobby command. It reports
+// whether a game was started, in which case the connection now belongs to
+// that game.
+func handleTournamentRequest(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string, args []string) (bool, error) {
+	command := "list"
+	if len(args) > 0 {
+		command, args = strings.ToLower(args[0]), args[1:]
+	}
+
+	switch command {
+	case "list":
+		return false, trySendMessage(conn, listTournaments(s))
+	case "play":
+		return handleTournamentPlay(s, conn, reader, nickname)
+	case "create":
+		return false, handleTournamentCreate(s, conn, reader, nickname, args)
+	}
+
+	if len(args) != 1 {
+		return false, trySendMessage(conn, tournamentUsage)
+	}
+
+	s.TournamentsMu.Lock()
+	t, ok := s.Tournaments[strings.ToUpper(args[0])]
+	if !ok {
+		s.TournamentsMu.Unlock()
+		return false, trySendMessage(conn, fmt.Sprintf("There is no tournament %s.\r\n", args[0]))
+	}
+
+	var message string
+	switch command {
+	case "show":
+		message = describeTournament(t)
+	case "join":
+		message = registerForTournament(t, nickname)
+	case "leave":
+		message = leaveTournament(t, nickname)
+	case "start":
+		message = startTournamentEarly(s, t, nickname)
+	default:
+		message = tournamentUsage
+	}
+	s.TournamentsMu.Unlock()
+
+	return false, trySendMessage(conn, message)
+}
+
+func handleTournamentCreate(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string, args []string) error {
+	if !s.Admins[nickname] {
+		return trySendMessage(conn, "Only administrators can create tournaments.\r\n")
+	}
+
+	names := make([]string, len(tournament.Formats))
+	for i, format := range tournament.Formats {
+		names[i] = string(format)
+	}
+	if len(args) != 2 {
+		return trySendMessage(conn, fmt.Sprintf("Usage: tournament create <%s> <registration minutes>\r\n", strings.Join(names, "|")))
+	}
+	format, err := tournament.ParseFormat(args[0])
+	if err != nil {
+		return trySendMessage(conn, fmt.Sprintf("Invalid format: %s. Choose one of %s.\r\n", err.Error(), strings.Join(names, ", ")))
+	}
+	minutes, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || minutes <= 0 || minutes > 24*60 {
+		return trySendMessage(conn, "The registration window must be a number of minutes up to a day.\r\n")
+	}
+
+	settings, err := requestSettings(conn, reader)
+	if err != nil {
+		return err
+	}
+	settings.Rated, err = requestRated(conn, reader)
+	if err != nil {
+		return err
+	}
+
+	s.TournamentsMu.Lock()
+	s.NextTournament++
+	settings.Tournament = fmt.Sprintf("T%d", s.NextTournament)
+	t := &models.Tournament{
+		ID:               settings.Tournament,
+		Format:           format,
+		Settings:         settings,
+		Admin:            nickname,
+		Status:           models.TournamentRegistration,
+		RegistrationEnds: time.Now().Add(time.Duration(minutes * float64(time.Minute))).Round(time.Second),
+		CheckIns:         make(map[*tournament.Pairing]*models.CheckIn),
+		Playing:          make(map[*tournament.Pairing]bool),
+		Start:            make(chan struct{}, 1),
+		Updates:          make(chan struct{}, 1),
+	}
+	s.Tournaments[t.ID] = t
+	s.TournamentsMu.Unlock()
+
+	log.Printf("%s created %s tournament %s (%s)", nickname, format.Description(), t.ID, describeSettings(settings))
+	go runTournament(s, t)
+
+	announcement := fmt.Sprintf("\r\n%s opened a %s tournament %s (%s). Type 'tournament join %s' within %s to take part.\r\n",
+		nickname, format.Description(), t.ID, describeSettings(settings), t.ID, time.Until(t.RegistrationEnds).Round(time.Second))
+	notifyLobby(s, announcement, nickname)
+	return trySendMessage(conn, fmt.Sprintf("Tournament %s created. Registration closes at %s; type 'tournament start %s' to close it earlier.\r\n",
+		t.ID, t.RegistrationEnds.Format("15:04:05"), t.ID))
+}
+
+func registerForTournament(t *models.Tournament, nickname string) string {
+	if t.Status != models.TournamentRegistration {
+		return fmt.Sprintf("Registration for tournament %s is closed.\r\n", t.ID)
+	}
+	for _, player := range t.Players {
+		if player == nickname {
+			return fmt.Sprintf("You are already registered for tournament %s.\r\n", t.ID)
+		}
+	}
+	t.Players = append(t.Players, nickname)
+	return fmt.Sprintf("You are registered for tournament %s (registered players: %d). Pairings will be announced when registration closes.\r\n", t.ID, len(t.Players))
+}
+
+func leaveTournament(t *models.Tournament, nickname string) string {
+	if t.Status != models.TournamentRegistration {
+		return fmt.Sprintf("Tournament %s has already started.\r\n", t.ID)
+	}
+	for i, player := range t.Players {
+		if player == nickname {
+			t.Players = append(t.Players[:i], t.Players[i+1:]...)
+			return fmt.Sprintf("You left tournament %s.\r\n", t.ID)
+		}
+	}
+	return fmt.Sprintf("You are not registered for tournament %s.\r\n", t.ID)
+}
+
+func startTournamentEarly(s *models.Server, t *models.Tournament, nickname string) string {
+	if !s.Admins[nickname] {
+		return "Only administrators can start tournaments.\r\n"
+	}
+	if t.Status != models.TournamentRegistration {
+		return fmt.Sprintf("Tournament %s is not open for registration.\r\n", t.ID)
+	}
+	select {
+	case t.Start <- struct{}{}:
+	default:
+	}
+	return fmt.Sprintf("Closing registration for tournament %s.\r\n", t.ID)
+}
+
+// runTournament waits for registration to close and then plays the
+// tournament round by round.
+func runTournament(s *models.Server, t *models.Tournament) {
+	timer := time.NewTimer(time.Until(t.RegistrationEnds))
+	select {
+	case <-timer.C:
+	case <-t.Start:
+		timer.Stop()
+	}
+
+	s.TournamentsMu.Lock()
+	players := append([]string(nil), t.Players...)
+	s.TournamentsMu.Unlock()
+
+	bracket, err := tournament.New(t.Format, seedPlayers(s, players))
+
+	s.TournamentsMu.Lock()
+	if err != nil {
+		t.Status = models.TournamentCancelled
+		s.TournamentsMu.Unlock()
+		log.Printf("tournament %s was cancelled: %v", t.ID, err)
+		notifyPlayers(s, players, fmt.Sprintf("\r\nTournament %s was cancelled: %s.\r\n", t.ID, err.Error()))
+		return
+	}
+	t.Bracket = bracket
+	t.Status = models.TournamentRunning
+	s.TournamentsMu.Unlock()
+
+	log.Printf("tournament %s started with %d players", t.ID, len(players))
+
+	for {
+		s.TournamentsMu.Lock()
+		round := bracket.NextRound()
+		if round == nil {
+			t.Status = models.TournamentFinished
+			winner, ok := bracket.Winner()
+			s.TournamentsMu.Unlock()
+
+			if !ok {
+				log.Printf("tournament %s finished without a winner", t.ID)
+				notifyPlayers(s, players, fmt.Sprintf("\r\nTournament %s is over without a winner, as nobody showed up for the last games. Type 'tournament show %s' for the final standings.\r\n", t.ID, t.ID))
+				return
+			}
+			log.Printf("tournament %s finished, %s won", t.ID, winner)
+			notifyPlayers(s, players, fmt.Sprintf("\r\nTournament %s is over. %s wins! Type 'tournament show %s' for the final standings.\r\n", t.ID, winner, t.ID))
+			return
+		}
+		t.RoundDeadline = time.Now().Add(s.TournamentCheckIn)
+		s.TournamentsMu.Unlock()
+
+		announceRound(s, t, round)
+		playRound(s, t)
+	}
+}
+
+// seedPlayers orders players by rating, strongest first.
+func seedPlayers(s *models.Server, players []string) []string {
+	ratings := make(map[string]float64)
+	for _, player := range players {
+		if r, err := PlayerRating(s.DB, player); err == nil {
+			ratings[player] = r.Rating
+		}
+	}
+	seeded := append([]string(nil), players...)
+	sort.SliceStable(seeded, func(i, j int) bool { return ratings[seeded[i]] > ratings[seeded[j]] })
+	return seeded
+}
+
+func announceRound(s *models.Server, t *models.Tournament, round []*tournament.Pairing) {
+	for _, p := range round {
+		label := fmt.Sprintf("Round %d of tournament %s", p.Round, t.ID)
+		if p.Bracket != "" {
+			label += fmt.Sprintf(" (%s bracket)", p.Bracket)
+		}
+		if p.Bye() {
+			notifyPlayer(s, p.First, fmt.Sprintf("\r\n%s: you have a bye this round.\r\n", label))
+			continue
+		}
+		for _, player := range []string{p.First, p.Second} {
+			order := "second"
+			if player == p.First {
+				order = "first"
+			}
+			notifyPlayer(s, player, fmt.Sprintf("\r\n%s: you play %s and move %s. Type 'tournament play' within %s to start the game.\r\n",
+				label, p.Opponent(player), order, s.TournamentCheckIn))
+		}
+	}
+}
+
+// playRound waits until every game of the current round has a result.
+// Pairings still unplayed at the check-in deadline are decided by forfeit.
+func playRound(s *models.Server, t *models.Tournament) {
+	s.TournamentsMu.Lock()
+	deadline := time.NewTimer(time.Until(t.RoundDeadline))
+	s.TournamentsMu.Unlock()
+	defer deadline.Stop()
+
+	for {
+		s.TournamentsMu.Lock()
+		complete := t.Bracket.RoundComplete()
+		s.TournamentsMu.Unlock()
+		if complete {
+			return
+		}
+
+		select {
+		case <-t.Updates:
+		case <-deadline.C:
+			forfeitNoShows(s, t)
+		}
+	}
+}
+
+func forfeitNoShows(s *models.Server, t *models.Tournament) {
+	s.TournamentsMu.Lock()
+	defer s.TournamentsMu.Unlock()
+
+	for _, p := range t.Bracket.Current() {
+		if p.Bye() || p.Result != tournament.Pending || t.Playing[p] {
+			continue
+		}
+		p.Forfeit = true
+		p.Result = tournament.BothLose
+		if checkIn, ok := t.CheckIns[p]; ok {
+			p.Result = resultFor(p, checkIn.Player.NickName)
+			delete(t.CheckIns, p)
+			checkIn.Answer <- nil
+		}
+		log.Printf("round %d of tournament %s: %s vs %s decided by forfeit", p.Round, t.ID, p.First, p.Second)
+	}
+}
+
+func resultFor(p *tournament.Pairing, winner string) tournament.Result {
+	if winner == p.First {
+		return tournament.FirstWins
+	}
+	return tournament.SecondWins
+}
+
+// handleTournamentPlay checks the player in for their current tournament
+// game and starts it once the opponent has checked in too.
+func handleTournamentPlay(s *models.Server, conn net.Conn, reader *bufio.Reader, nickname string) (bool, error) {
+	s.TournamentsMu.Lock()
+	t, p := findTournamentPairing(s, nickname)
+	if p == nil {
+		s.TournamentsMu.Unlock()
+		return false, trySendMessage(conn, "You have no tournament game to play right now.\r\n")
+	}
+
+	player := models.Player{
+		IP:       conn.RemoteAddr().String(),
+		Conn:     conn,
+		NickName: nickname,
+		Settings: t.Settings,
+	}
+
+	if waiting, ok := t.CheckIns[p]; ok {
+		delete(t.CheckIns, p)
+		t.Playing[p] = true
+		s.TournamentsMu.Unlock()
+
+		setInLobby(s, nickname, conn, false)
+		waiting.Answer <- &player
+		return true, nil
+	}
+
+	checkIn := &models.CheckIn{Player: player, Answer: make(chan *models.Player, 1)}
+	t.CheckIns[p] = checkIn
+	deadline := t.RoundDeadline
+	s.TournamentsMu.Unlock()
+
+	setInLobby(s, nickname, conn, false)
+	opponent := p.Opponent(nickname)
+	notifyPlayer(s, opponent, fmt.Sprintf("\r\n%s is ready for your round %d game in tournament %s. Type 'tournament play' to start it.\r\n", nickname, p.Round, t.ID))
+	if err := trySendMessage(conn, fmt.Sprintf("Waiting for %s until %s (type 'cancel' to stop waiting)...\r\n", opponent, deadline.Format("15:04:05"))); err != nil {
+		withdrawCheckIn(s, t, p, checkIn)
+		return false, err
+	}
+
+	withdraw := func() bool { return withdrawCheckIn(s, t, p, checkIn) }
+	answer, err := awaitOpponent(conn, reader, time.Until(deadline), checkIn.Answer, withdraw, fmt.Sprintf("Still waiting for %s. Type 'cancel' to stop waiting.\r\n", opponent))
+	if answer != nil {
+		go startTournamentGame(s, p, player, *answer)
+		return true, nil
+	}
+	if err != nil || time.Now().Before(deadline) {
+		return false, err
+	}
+
+	s.TournamentsMu.Lock()
+	if p.Result == tournament.Pending && !t.Playing[p] {
+		p.Forfeit = true
+		p.Result = resultFor(p, nickname)
+		signalTournament(t)
+	}
+	won := p.Forfeit && p.Result == resultFor(p, nickname)
+	s.TournamentsMu.Unlock()
+
+	if won {
+		return false, trySendMessage(conn, fmt.Sprintf("%s did not show up, so you win by forfeit.\r\n", opponent))
+	}
+	return false, nil
+}
+
+func withdrawCheckIn(s *models.Server, t *models.Tournament, p *tournament.Pairing, checkIn *models.CheckIn) bool {
+	s.TournamentsMu.Lock()
+	defer s.TournamentsMu.Unlock()
+
+	if t.CheckIns[p] != checkIn {
+		return false
+	}
+	delete(t.CheckIns, p)
+	return true
+}
+
+// findTournamentPairing returns the player's unplayed game in the current
+// round of a running tournament. The caller must hold s.TournamentsMu.
+func findTournamentPairing(s *models.Server, nickname string) (*models.Tournament, *tournament.Pairing) {
+	for _, t := range s.Tournaments {
+		if t.Status != models.TournamentRunning {
+			continue
+		}
+		for _, p := range t.Bracket.Current() {
+			if p.Has(nickname) && !p.Bye() && p.Result == tournament.Pending && !t.Playing[p] {
+				return t, p
+			}
+		}
+	}
+	return nil, nil
+}
+
+func startTournamentGame(s *models.Server, p *tournament.Pairing, a models.Player, b models.Player) {
+	player1, player2 := a, b
+	if b.NickName == p.First {
+		player1, player2 = b, a
+	}
+
+	variant, ok := engine.LookupVariant(player1.Settings.Variant)
+	if !ok {
+		variant = engine.Classic
+	}
+	player1.Symbol = variant.SideName(engine.First)
+	player2.Symbol = variant.SideName(engine.Second)
+
+	log.Printf("creating round %d game of tournament %s with %s and %s", p.Round, player1.Settings.Tournament, player1.NickName, player2.NickName)
+
+	StartGame(player1, player2, variant, s)
+}
+
+// recordTournamentResult feeds a finished game back into its tournament.
+// A game that ended in an error counts as a draw.
+func recordTournamentResult(s *models.Server, result models.GameResult) {
+	s.TournamentsMu.Lock()
+	defer s.TournamentsMu.Unlock()
+
+	t, ok := s.Tournaments[result.Player1.Settings.Tournament]
+	if !ok || t.Bracket == nil {
+		return
+	}
+
+	for _, p := range t.Bracket.Current() {
+		if !t.Playing[p] || !p.Has(result.Player1.NickName) || !p.Has(result.Player2.NickName) {
+			continue
+		}
+		delete(t.Playing, p)
+
+		p.Result = tournament.Draw
+		if result.Error == nil && result.Winner != nil {
+			p.Result = resultFor(p, result.Winner.NickName)
+		}
+		p.Forfeit = result.Reason == models.EndForfeit
+		signalTournament(t)
+		return
+	}
+}
+
+func signalTournament(t *models.Tournament) {
+	select {
+	case t.Updates <- struct{}{}:
+	default:
+	}
+}
+
+// listTournaments summarises the tournaments that are open or running.
+func listTournaments(s *models.Server) string {
+	s.TournamentsMu.Lock()
+	defer s.TournamentsMu.Unlock()
+
+	ids := make([]string, 0, len(s.Tournaments))
+	for id := range s.Tournaments {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return len(ids[i]) < len(ids[j]) || (len(ids[i]) == len(ids[j]) && ids[i] < ids[j])
+	})
+
+	if len(ids) == 0 {
+		return "There are no tournaments.\r\n"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("\r\nTournaments:\r\n")
+	for _, id := range ids {
+		t := s.Tournaments[id]
+		builder.WriteString(fmt.Sprintf("  %-4s %-19s %-32s %d players, %s\r\n", t.ID, t.Format.Description(), describeTournamentStatus(t), len(t.Players), describeSettings(t.Settings)))
+	}
+	return builder.String()
+}
+
+func describeTournamentStatus(t *models.Tournament) string {
+	switch t.Status {
+	case models.TournamentRegistration:
+		return fmt.Sprintf("registration open for %s", time.Until(t.RegistrationEnds).Round(time.Second))
+	case models.TournamentRunning:
+		if total := t.Bracket.TotalRounds(); total > 0 {
+			return fmt.Sprintf("round %d of %d", len(t.Bracket.Rounds), total)
+		}
+		return fmt.Sprintf("round %d", len(t.Bracket.Rounds))
+	}
+	return t.Status
+}
+
+// describeTournament renders the standings and every round played so far.
+// The caller must hold s.TournamentsMu.
+func describeTournament(t *models.Tournament) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("\r\nTournament %s: %s, %s, %s\r\n", t.ID, t.Format.Description(), describeSettings(t.Settings), describeTournamentStatus(t)))
+
+	if t.Bracket == nil {
+		builder.WriteString(fmt.Sprintf("Registered (%d): %s\r\n", len(t.Players), strings.Join(t.Players, ", ")))
+		return builder.String()
+	}
+
+	builder.WriteString("Standings:\r\n")
+	if t.Format.Elimination() {
+		builder.WriteString(fmt.Sprintf("    %-20s %3s %3s %3s  %s\r\n", "Player", "W", "D", "L", "Status"))
+	} else {
+		builder.WriteString(fmt.Sprintf("    %-20s %4s %3s %3s %3s  %s\r\n", "Player", "Pts", "W", "D", "L", "Buchholz"))
+	}
+	for i, standing := range t.Bracket.Standings() {
+		if t.Format.Elimination() {
+			status := "in"
+			if standing.Eliminated {
+				status = "out"
+			}
+			builder.WriteString(fmt.Sprintf("%2d. %-20s %3d %3d %3d  %s\r\n", i+1, standing.Player, standing.Wins, standing.Draws, standing.Losses, status))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%2d. %-20s %4.1f %3d %3d %3d  %.1f\r\n", i+1, standing.Player, standing.Points, standing.Wins, standing.Draws, standing.Losses, standing.Tiebreak))
+	}
+
+	for i, round := range t.Bracket.Rounds {
+		builder.WriteString(fmt.Sprintf("Round %d:\r\n", i+1))
+		for _, p := range round {
+			builder.WriteString("  " + describePairing(t, p) + "\r\n")
+		}
+	}
+	return builder.String()
+}
+
+func describePairing(t *models.Tournament, p *tournament.Pairing) string {
+	prefix := ""
+	if p.Bracket != "" {
+		prefix = fmt.Sprintf("[%s] ", p.Bracket)
+	}
+	if p.Bye() {
+		return fmt.Sprintf("%s%s has a bye", prefix, p.First)
+	}
+
+	score := "-"
+	switch p.Result {
+	case tournament.FirstWins:
+		score = "1-0"
+	case tournament.SecondWins:
+		score = "0-1"
+	case tournament.Draw:
+		score = "½-½"
+	case tournament.BothLose:
+		score = "0-0"
+	}
+	line := fmt.Sprintf("%s%s %s %s", prefix, p.First, score, p.Second)
+
+	switch {
+	case p.Result == tournament.Pending && t.Playing[p]:
+		line += " (playing)"
+	case p.Result == tournament.Pending:
+		line += " (not started)"
+	case p.Forfeit:
+		line += " (forfeit)"
+	}
+	if p.Result == tournament.Draw && t.Format.Elimination() {
+		line += fmt.Sprintf(", %s advances as the higher seed", t.Bracket.Advances(p))
+	}
+	return line
+}
+
+func notifyPlayers(s *models.Server, players []string, message string) {
+	for _, player := range players {
+		notifyPlayer(s, player, message)
+	}
+}
+
+// notifyLobby sends a message to everyone at the menu except one player.
+func notifyLobby(s *models.Server, message string, except string) {
+	s.ActiveUsersMu.Lock()
+	var players []string
+	for nickname, session := range s.ActiveUsers {
+		if session.InLobby && session.Conn != nil && nickname != except {
+			players = append(players, nickname)
+		}
+	}
+	s.ActiveUsersMu.Unlock()
+
+	notifyPlayers(s, players, message)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	webSocketGUID       = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	maxWebSocketMessage = 64 * 1024
+
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+var errWebSocketProtocol = errors.New("websocket protocol error")
+
+// wsConn is a server-side WebSocket connection that behaves like the raw
+// TCP connections the lobby and game code were written for. Every message
+// from the client is one line of input, with any line breaks inside it
+// turned into spaces, and every Write is sent as one text message. Like
+// jsonConn, Read returns at most one line, so input that a caller's
+// buffered reader takes is never more than the line it asked for. Frames
+// that arrive only partly before a read deadline are kept for the next
+// call.
+type wsConn struct {
+	net.Conn
+	raw       []byte
+	message   []byte
+	pending   []byte
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+func handleWebSocket(s *models.Server, w http.ResponseWriter, r *http.Request) {
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return
+	}
+
+	conn, buffered, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		log.Printf("websocket hijack failed: %v", err)
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + webSocketAccept(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		log.Printf("websocket handshake with %s failed: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+
+	ws := &wsConn{Conn: conn}
+	if n := buffered.Reader.Buffered(); n > 0 {
+		ws.raw, _ = buffered.Reader.Peek(n)
+		ws.raw = append([]byte(nil), ws.raw...)
+	}
+
+	log.Printf("new websocket connection from %s", conn.RemoteAddr())
+
+	if r.URL.Query().Get("protocol") == "json" {
+		client, err := switchToJSON(ws, bufio.NewReader(ws))
+		if err != nil {
+			ws.Close()
+			return
+		}
+		handleWelcome(s, client, bufio.NewReader(client))
+		return
+	}
+	handleNewConn(s, ws)
+}
+
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func webSocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if err := c.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	end := bytes.IndexByte(c.pending, '\n') + 1
+	n := copy(p, c.pending[:end])
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// readFrame handles the next complete frame, reading from the connection
+// until one is buffered.
+func (c *wsConn) readFrame() error {
+	for {
+		fin, opcode, payload, size, err := parseFrame(c.raw)
+		if err != nil {
+			c.closeWith(1002)
+			return err
+		}
+		if size > 0 {
+			c.raw = c.raw[size:]
+			return c.handleFrame(fin, opcode, payload)
+		}
+
+		buf := make([]byte, 4096)
+		n, err := c.Conn.Read(buf)
+		c.raw = append(c.raw, buf[:n]...)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseFrame decodes the frame at the start of data. A size of zero means
+// the frame is not complete yet.
+func parseFrame(data []byte) (fin bool, opcode byte, payload []byte, size int, err error) {
+	if len(data) < 2 {
+		return false, 0, nil, 0, nil
+	}
+	fin = data[0]&0x80 != 0
+	opcode = data[0] & 0x0F
+	if data[0]&0x70 != 0 || data[1]&0x80 == 0 {
+		return false, 0, nil, 0, errWebSocketProtocol
+	}
+
+	header := 2
+	length := uint64(data[1] & 0x7F)
+	switch length {
+	case 126:
+		header += 2
+		if len(data) < header {
+			return false, 0, nil, 0, nil
+		}
+		length = uint64(binary.BigEndian.Uint16(data[2:4]))
+	case 127:
+		header += 8
+		if len(data) < header {
+			return false, 0, nil, 0, nil
+		}
+		length = binary.BigEndian.Uint64(data[2:10])
+	}
+	if length > maxWebSocketMessage {
+		return false, 0, nil, 0, fmt.Errorf("%w: frame of %d bytes is too large", errWebSocketProtocol, length)
+	}
+
+	header += 4
+	total := header + int(length)
+	if len(data) < total {
+		return false, 0, nil, 0, nil
+	}
+
+	mask := data[header-4 : header]
+	payload = make([]byte, length)
+	for i := range payload {
+		payload[i] = data[header+i] ^ mask[i%4]
+	}
+	return fin, opcode, payload, total, nil
+}
+
+func (c *wsConn) handleFrame(fin bool, opcode byte, payload []byte) error {
+	switch opcode {
+	case opText, opBinary, opContinuation:
+		if (opcode == opContinuation) != (c.message != nil) {
+			c.closeWith(1002)
+			return errWebSocketProtocol
+		}
+		if len(c.message)+len(payload) > maxWebSocketMessage {
+			c.closeWith(1009)
+			return fmt.Errorf("%w: message is too large", errWebSocketProtocol)
+		}
+		c.message = append(c.message, payload...)
+		if c.message == nil {
+			c.message = []byte{}
+		}
+		if !fin {
+			return nil
+		}
+
+		line := strings.TrimRight(string(c.message), "\r\n")
+		line = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(line)
+		c.message = nil
+		c.pending = append(c.pending, line+"\n"...)
+		return nil
+	case opPing:
+		return c.writeFrame(opPong, payload)
+	case opPong:
+		return nil
+	case opClose:
+		c.closeWith(1000)
+		return io.EOF
+	default:
+		c.closeWith(1002)
+		return errWebSocketProtocol
+	}
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	text := p
+	if !utf8.Valid(text) {
+		text = []byte(strings.ToValidUTF8(string(p), "?"))
+	}
+	if err := c.writeFrame(opText, text); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	frame = append(frame, payload...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.Conn.Write(frame)
+	return err
+}
+
+// closeWith sends a close frame with the given status code and closes the
+// connection.
+func (c *wsConn) closeWith(code uint16) {
+	c.closeOnce.Do(func() {
+		c.Conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, code))
+		c.Conn.Close()
+	})
+}
+
+func (c *wsConn) Close() error {
+	c.closeWith(1000)
+	return nil
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"bufio"
+	"net"
+	"testing"
+)
+
+// clientFrame encodes a masked text frame, as a browser sends it.
+func clientFrame(text string) []byte {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opText, 0x80 | byte(len(text))}
+	frame = append(frame, mask...)
+	for i := 0; i < len(text); i++ {
+		frame = append(frame, text[i]^mask[i%4])
+	}
+	return frame
+}
+
+func TestWebSocketReadReturnsOneLine(t *testing.T) {
+	server, client := net.Pipe()
+	ws := &wsConn{Conn: server}
+	defer ws.Close()
+	defer client.Close()
+
+	var frames []byte
+	for _, message := range []string{"play", "B2", "two\nlines\r\n"} {
+		frames = append(frames, clientFrame(message)...)
+	}
+	ws.raw = frames
+
+	buf := make([]byte, 1024)
+	for _, want := range []string{"play\n", "B2\n", "two lines\n"} {
+		n, err := ws.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != want {
+			t.Errorf("Read() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestWebSocketBufferedReaderLeavesLaterLines(t *testing.T) {
+	server, client := net.Pipe()
+	ws := &wsConn{Conn: server}
+	defer ws.Close()
+	defer client.Close()
+	ws.raw = append(clientFrame("play"), clientFrame("B2")...)
+
+	line, err := bufio.NewReader(ws).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "play\n" {
+		t.Fatalf("ReadString = %q, want %q", line, "play\n")
+	}
+
+	buf := make([]byte, 1024)
+	n, err := ws.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "B2\n" {
+		t.Errorf("the next Read = %q, want %q", got, "B2\n")
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Challenge is an invitation from one player to another. The invited
+// player claims it by removing it from Server.Challenges and answering with
+// themselves, or with nil to decline.
+type Challenge struct {
+	From     Player
+	To       string
+	Settings GameSettings
+	Created  time.Time
+	Answer   chan *Player
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type TimeControl struct {
+	Base      time.Duration
+	Increment time.Duration
+}
+
+func (tc TimeControl) Enabled() bool {
+	return tc.Base > 0
+}
+
+// Clock holds the time left for Player1 and Player2, in that order.
+type Clock struct {
+	Remaining [2]time.Duration
+	Increment time.Duration
+}
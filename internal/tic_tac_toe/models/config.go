@@ -1,9 +1,24 @@
 package models
 
+import "time"
+
 type Config struct {
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
+
+	BotFillTimeout time.Duration
+	BotFillLevel   string
+	BotThinkTime   time.Duration
+	BotIterations  int
+	ReconnectGrace time.Duration
+	SessionPolicy  string
+	RatingMinGames int
+
+	Admins            []string
+	TournamentCheckIn time.Duration
+
+	HTTPAddr string
 }
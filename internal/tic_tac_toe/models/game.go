@@ -1,26 +1,84 @@
 package models
 
+import (
+	"sync"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"time"
+)
+
+type EndReason string
+
+const (
+	EndNormal      EndReason = "normal"
+	EndTimeout     EndReason = "timeout"
+	EndResignation EndReason = "resignation"
+	EndAgreement   EndReason = "agreement"
+	EndForfeit     EndReason = "forfeit"
+)
+
+type MoveRecord struct {
+	Move     engine.Move
+	Side     engine.Side
+	Notation string
+	At       time.Time
+}
+
 type Game struct {
 	ID            string
 	Player1       Player
 	Player2       Player
-	Board         *[3][3]string
+	Variant       engine.Variant
+	Settings      GameSettings
+	Position      engine.State
+	Moves         []MoveRecord
+	Clock         *Clock
+	DrawOffer     *Player
 	OnGoing       bool
+	Rated         bool
 	CurrentPlayer *Player
 	WaitingPlayer *Player
 	Winner        *Player
 	Loser         *Player
+	EndReason     EndReason
+	Started       time.Time
 
-	Spectators *map[Spectator]struct{}
+	// SpectatorsMu guards Spectators, which spectators join from their
+	// own goroutines while the game goroutine writes to them.
+	SpectatorsMu sync.Mutex
+	Spectators   *map[Spectator]struct{}
 
 	Error error
 }
 
 type GameResult struct {
-	GameID  string
-	Player1 Player
-	Player2 Player
-	Winner  *Player
-	Loser   *Player
-	Error   error
+	GameID     string
+	Player1    Player
+	Player2    Player
+	Winner     *Player
+	Loser      *Player
+	Reason     EndReason
+	AgainstBot bool
+	Rated      bool
+	Settings   GameSettings
+	Moves      []MoveRecord
+	Started    time.Time
+	Ended      time.Time
+	Error      error
+}
+
+// GameRecord is a finished game as stored in the games table.
+type GameRecord struct {
+	ID         string
+	Player1    string
+	Player2    string
+	Symbol1    string
+	Symbol2    string
+	Settings   GameSettings
+	AgainstBot bool
+	Started    time.Time
+	Ended      time.Time
+	Winner     string
+	Reason     EndReason
+	MoveCount  int
+	Moves      []MoveRecord
 }
@@ -1,10 +1,20 @@
 package models
 
-import "net"
+import (
+	"net"
+	"tic_tac_toe/internal/tic_tac_toe/bot"
+)
 
 type Player struct {
 	IP       string
 	Conn     net.Conn
 	NickName string
 	Symbol   string
+	Settings GameSettings
+	Rating   float64
+
+	Reconnect chan net.Conn
+	Watch     *QueueWatch
+
+	Bot bot.Player
 }
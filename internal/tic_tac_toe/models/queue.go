@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// QueueStatus describes one player waiting in the matchmaking queue.
+// Position counts only players who asked for the same settings.
+type QueueStatus struct {
+	NickName      string
+	Settings      GameSettings
+	Rating        float64
+	Position      int
+	Waited        time.Duration
+	RatingRange   float64
+	EstimatedWait time.Duration
+}
@@ -0,0 +1,21 @@
+package models
+
+import "tic_tac_toe/internal/tic_tac_toe/bot"
+
+// Rematch remembers a player's last game so it can be replayed with the
+// colours swapped.
+type Rematch struct {
+	Opponent string
+	Settings GameSettings
+	First    bool
+	Bot      bot.Player
+}
+
+// RematchOffer is a player's request to replay their last game. The
+// opponent claims it by removing it from Server.RematchOffers and answering
+// with themselves.
+type RematchOffer struct {
+	From   Player
+	To     string
+	Answer chan *Player
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+const (
+	VisibilityPublic = "public"
+	VisibilityHidden = "hidden"
+	VisibilityCode   = "code"
+)
+
+// Room is a private game waiting for the guest who knows its code. A guest
+// claims the room by removing it from Server.Rooms and sending themselves
+// on Join.
+type Room struct {
+	Code     string
+	Host     Player
+	Settings GameSettings
+	Created  time.Time
+	Join     chan *Player
+}
@@ -4,18 +4,51 @@ import (
 	"database/sql"
 	"net"
 	"sync"
+	"tic_tac_toe/internal/tic_tac_toe/bot"
+	"tic_tac_toe/internal/tic_tac_toe/solver"
+	"time"
 )
 
 type Server struct {
-	ListenAddr  string
-	Listener    net.Listener
-	ConnsChan   chan Player
-	ResultsChan chan GameResult
-	DB          *sql.DB
+	ListenAddr   string
+	Listener     net.Listener
+	HTTPAddr     string
+	ConnsChan    chan Player
+	ReattachChan chan Reattach
+	LeaveChan    chan LeaveQueue
+	QueueChan    chan chan []QueueStatus
+	ResultsChan  chan GameResult
+	DB           *sql.DB
+
+	BotFillTimeout time.Duration
+	BotFillLevel   string
+	BotOptions     bot.Options
+	Solver         *solver.Solver
 
 	ActiveGamesMu sync.Mutex
 	Games         map[string]*Game
 
 	ActiveUsersMu sync.Mutex
-	ActiveUsers   map[string]net.Conn
+	ActiveUsers   map[string]*Session
+	Sessions      map[string]*Session
+	SessionPolicy string
+
+	ReconnectGrace time.Duration
+	RatingMinGames int
+
+	ChallengesMu sync.Mutex
+	Challenges   map[string]*Challenge
+
+	Admins            map[string]bool
+	TournamentCheckIn time.Duration
+	TournamentsMu     sync.Mutex
+	Tournaments       map[string]*Tournament
+	NextTournament    int
+
+	RoomsMu sync.Mutex
+	Rooms   map[string]*Room
+
+	RematchMu     sync.Mutex
+	Rematches     map[string]Rematch
+	RematchOffers map[string]*RematchOffer
 }
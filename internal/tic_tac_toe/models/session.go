@@ -0,0 +1,49 @@
+package models
+
+import "net"
+
+const (
+	SessionReject   = "reject"
+	SessionTakeover = "takeover"
+)
+
+// Session is a logged-in nickname. The token lets a client attach a new
+// connection to it without entering the password again. Conn is nil while
+// the player is disconnected from a running game, and InLobby is set while
+// the player is at the menu and can receive invitations.
+type Session struct {
+	Token    string
+	NickName string
+	Conn     net.Conn
+	InLobby  bool
+}
+
+// Reattach asks the matchmaker to hand a queued slot over to a new
+// connection and the Watch reading it. Done reports whether the nickname
+// was still queued.
+type Reattach struct {
+	NickName string
+	Conn     net.Conn
+	Watch    *QueueWatch
+	Done     chan bool
+}
+
+// LeaveQueue asks the matchmaker to drop a queued player. Done reports
+// whether the nickname was still queued on Conn. A Disconnected player keeps
+// the slot for the reconnect grace period so that they can resume it, but
+// is not paired with anyone until they do.
+type LeaveQueue struct {
+	NickName     string
+	Conn         net.Conn
+	Disconnected bool
+	Done         chan bool
+}
+
+// QueueWatch belongs to the lobby goroutine that reads a queued player's
+// connection for 'cancel'. Whoever takes the player out of the queue for a
+// game sends on Handoff, so the game never reads the connection at the same
+// time; Done is closed once the lobby goroutine has stopped reading.
+type QueueWatch struct {
+	Handoff chan struct{}
+	Done    chan struct{}
+}
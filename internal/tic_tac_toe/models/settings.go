@@ -0,0 +1,18 @@
+package models
+
+import "tic_tac_toe/internal/tic_tac_toe/engine"
+
+type GameSettings struct {
+	Variant     string
+	Size        int
+	WinLength   int
+	TimeControl TimeControl
+	Rated       bool
+	Visibility  string
+	Room        string
+	Tournament  string
+}
+
+func ClassicSettings() GameSettings {
+	return GameSettings{Variant: engine.ClassicName, Size: 3, WinLength: 3, Rated: true}
+}
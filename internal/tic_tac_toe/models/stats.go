@@ -0,0 +1,30 @@
+package models
+
+import "tic_tac_toe/internal/tic_tac_toe/rating"
+
+type PlayerStats struct {
+	Nickname      string
+	Games         int
+	Wins          int
+	Losses        int
+	Draws         int
+	TimeoutLosses int
+	Resignations  int
+	RatedGames    int
+	Rating        rating.Rating
+}
+
+// WinRate is the share of games won, in percent.
+func (p PlayerStats) WinRate() float64 {
+	if p.Games == 0 {
+		return 0
+	}
+	return float64(p.Wins) / float64(p.Games) * 100
+}
+
+type LeaderboardEntry struct {
+	Rank       int
+	Nickname   string
+	Rating     rating.Rating
+	RatedGames int
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"tic_tac_toe/internal/tic_tac_toe/tournament"
+	"time"
+)
+
+const (
+	TournamentRegistration = "registration"
+	TournamentRunning      = "running"
+	TournamentFinished     = "finished"
+	TournamentCancelled    = "cancelled"
+)
+
+// CheckIn is a player waiting for their tournament opponent. The opponent
+// claims it by removing it from Tournament.CheckIns and sending themselves
+// on Answer.
+type CheckIn struct {
+	Player Player
+	Answer chan *Player
+}
+
+// Tournament is guarded by Server.TournamentsMu. Bracket is nil until
+// registration closes.
+type Tournament struct {
+	ID               string
+	Format           tournament.Format
+	Settings         GameSettings
+	Admin            string
+	Status           string
+	RegistrationEnds time.Time
+	Players          []string
+	Bracket          *tournament.Tournament
+	RoundDeadline    time.Time
+	CheckIns         map[*tournament.Pairing]*CheckIn
+	Playing          map[*tournament.Pairing]bool
+	Start            chan struct{}
+	Updates          chan struct{}
+}
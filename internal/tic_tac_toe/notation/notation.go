@@ -0,0 +1,487 @@
+package notation
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+const (
+	TagGame         = "Game"
+	TagDate         = "Date"
+	TagTime         = "Time"
+	TagFirst        = "First"
+	TagSecond       = "Second"
+	TagFirstSymbol  = "FirstSymbol"
+	TagSecondSymbol = "SecondSymbol"
+	TagVariant      = "Variant"
+	TagSize         = "Size"
+	TagWinLength    = "WinLength"
+	TagTimeControl  = "TimeControl"
+	TagRated        = "Rated"
+	TagResult       = "Result"
+	TagTermination  = "Termination"
+)
+
+const (
+	FirstWins  = "1-0"
+	SecondWins = "0-1"
+	Draw       = "1/2-1/2"
+	Unknown    = "*"
+)
+
+const (
+	dateLayout = "2006.01.02"
+	timeLayout = "15:04:05"
+	lineWidth  = 79
+
+	unknownPlayer = "?"
+)
+
+var (
+	tagPattern        = regexp.MustCompile(`^\[([A-Za-z][A-Za-z0-9_]*)\s+"((?:[^"\\]|\\.)*)"\]$`)
+	moveNumberPattern = regexp.MustCompile(`^(\d+)\.(\.\.)?`)
+	commentPattern    = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// Game is a game written down in text notation: header tags in the order
+// they appear, followed by the moves in the notation players type.
+type Game struct {
+	Tags   []Tag
+	Moves  []string
+	Result string
+}
+
+func (g *Game) Tag(name string) string {
+	for _, tag := range g.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+func (g *Game) hasTag(name string) bool {
+	for _, tag := range g.Tags {
+		if tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) SetTag(name, value string) {
+	for i, tag := range g.Tags {
+		if tag.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, Tag{Name: name, Value: value})
+}
+
+// FromRecord converts a stored game into notation.
+func FromRecord(record models.GameRecord) *Game {
+	g := &Game{Result: recordResult(record)}
+
+	started := record.Started.UTC()
+	g.SetTag(TagGame, record.ID)
+	g.SetTag(TagDate, started.Format(dateLayout))
+	g.SetTag(TagTime, started.Format(timeLayout))
+	g.SetTag(TagFirst, record.Player1)
+	g.SetTag(TagSecond, record.Player2)
+	g.SetTag(TagFirstSymbol, record.Symbol1)
+	g.SetTag(TagSecondSymbol, record.Symbol2)
+	g.SetTag(TagVariant, record.Settings.Variant)
+	g.SetTag(TagSize, strconv.Itoa(record.Settings.Size))
+	g.SetTag(TagWinLength, strconv.Itoa(record.Settings.WinLength))
+	g.SetTag(TagTimeControl, formatTimeControl(record.Settings.TimeControl))
+	g.SetTag(TagRated, formatBool(record.Settings.Rated))
+	g.SetTag(TagResult, g.Result)
+	g.SetTag(TagTermination, string(record.Reason))
+
+	for _, move := range record.Moves {
+		g.Moves = append(g.Moves, move.Notation)
+	}
+	return g
+}
+
+func recordResult(record models.GameRecord) string {
+	switch record.Winner {
+	case "":
+		return Draw
+	case record.Player1:
+		return FirstWins
+	case record.Player2:
+		return SecondWins
+	}
+	return Unknown
+}
+
+// String writes the game as header tags, a blank line and the numbered
+// move list, ending with the result.
+func (g *Game) String() string {
+	var b strings.Builder
+	for _, tag := range g.Tags {
+		b.WriteString(fmt.Sprintf("[%s \"%s\"]\n", tag.Name, escape(tag.Value)))
+	}
+	b.WriteString("\n")
+
+	result := g.Result
+	if result == "" {
+		result = Unknown
+	}
+
+	tokens := make([]string, 0, len(g.Moves)+1)
+	for i := 0; i < len(g.Moves); i += 2 {
+		token := fmt.Sprintf("%d. %s", i/2+1, g.Moves[i])
+		if i+1 < len(g.Moves) {
+			token += " " + g.Moves[i+1]
+		}
+		tokens = append(tokens, token)
+	}
+	tokens = append(tokens, result)
+
+	line := 0
+	for i, token := range tokens {
+		if i > 0 {
+			if line+1+len(token) > lineWidth {
+				b.WriteString("\n")
+				line = 0
+			} else {
+				b.WriteString(" ")
+				line++
+			}
+		}
+		b.WriteString(token)
+		line += len(token)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// Parse reads a game in text notation. Comments in braces are ignored.
+func Parse(text string) (*Game, error) {
+	g := &Game{}
+
+	var movetext strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	inHeader := true
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if inHeader && strings.HasPrefix(line, "[") {
+			match := tagPattern.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("line %d: malformed tag %q", lineNumber, line)
+			}
+			if g.hasTag(match[1]) {
+				return nil, fmt.Errorf("line %d: duplicate tag %s", lineNumber, match[1])
+			}
+			g.Tags = append(g.Tags, Tag{Name: match[1], Value: unescape(match[2])})
+			continue
+		}
+		if line != "" {
+			inHeader = false
+		}
+		movetext.WriteString(line)
+		movetext.WriteString(" ")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := g.parseMoves(movetext.String()); err != nil {
+		return nil, err
+	}
+
+	if tagged := g.Tag(TagResult); tagged != "" {
+		if !validResult(tagged) {
+			return nil, fmt.Errorf("invalid result tag %q", tagged)
+		}
+		if g.Result == "" {
+			g.Result = tagged
+		} else if g.Result != tagged {
+			return nil, fmt.Errorf("result %s does not match the result tag %s", g.Result, tagged)
+		}
+	}
+	if g.Result == "" {
+		g.Result = Unknown
+	}
+	return g, nil
+}
+
+func (g *Game) parseMoves(text string) error {
+	text = commentPattern.ReplaceAllString(text, " ")
+	if strings.ContainsAny(text, "{}") {
+		return fmt.Errorf("unterminated comment")
+	}
+
+	for _, token := range strings.Fields(text) {
+		if g.Result != "" {
+			return fmt.Errorf("unexpected %q after the result", token)
+		}
+		if validResult(token) {
+			g.Result = token
+			continue
+		}
+
+		if match := moveNumberPattern.FindStringSubmatch(token); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			if n != len(g.Moves)/2+1 {
+				return fmt.Errorf("move number %d out of sequence, expected %d", n, len(g.Moves)/2+1)
+			}
+			token = token[len(match[0]):]
+			if token == "" {
+				continue
+			}
+		}
+		g.Moves = append(g.Moves, token)
+	}
+	return nil
+}
+
+// Record converts the game back into the form it is stored in. The moves
+// are checked against the rules of the variant.
+func (g *Game) Record() (models.GameRecord, error) {
+	record := models.GameRecord{
+		ID:      g.Tag(TagGame),
+		Player1: g.Tag(TagFirst),
+		Player2: g.Tag(TagSecond),
+		Symbol1: g.Tag(TagFirstSymbol),
+		Symbol2: g.Tag(TagSecondSymbol),
+		Reason:  models.EndReason(g.Tag(TagTermination)),
+	}
+	switch record.Reason {
+	case "":
+		record.Reason = models.EndNormal
+	case models.EndNormal, models.EndTimeout, models.EndResignation, models.EndAgreement, models.EndForfeit:
+	default:
+		return models.GameRecord{}, fmt.Errorf("unknown termination %q", record.Reason)
+	}
+	if record.Player1 == "" {
+		record.Player1 = unknownPlayer
+	}
+	if record.Player2 == "" {
+		record.Player2 = unknownPlayer
+	}
+
+	settings, err := g.settings()
+	if err != nil {
+		return models.GameRecord{}, err
+	}
+	record.Settings = settings
+
+	variant, _ := engine.LookupVariant(settings.Variant)
+	if record.Symbol1 == "" {
+		record.Symbol1 = variant.SideName(engine.First)
+	}
+	if record.Symbol2 == "" {
+		record.Symbol2 = variant.SideName(engine.Second)
+	}
+
+	if date := g.Tag(TagDate); date != "" {
+		layout, value := dateLayout, date
+		if clock := g.Tag(TagTime); clock != "" {
+			layout, value = dateLayout+" "+timeLayout, date+" "+clock
+		}
+		started, err := time.Parse(layout, value)
+		if err != nil {
+			return models.GameRecord{}, fmt.Errorf("invalid date %q", value)
+		}
+		record.Started = started
+	}
+
+	position, err := variant.NewState(settings.Size, settings.WinLength)
+	if err != nil {
+		return models.GameRecord{}, err
+	}
+	for i, input := range g.Moves {
+		side := position.Turn()
+		move, err := position.ParseMove(input)
+		if err == nil {
+			err = position.Apply(move)
+		}
+		if err != nil {
+			return models.GameRecord{}, fmt.Errorf("move %d (%s): %w", i+1, input, err)
+		}
+		record.Moves = append(record.Moves, models.MoveRecord{Move: move, Side: side, Notation: position.FormatMove(move)})
+	}
+	record.MoveCount = len(record.Moves)
+
+	if err := checkResult(g.Result, record.Reason, position.Outcome()); err != nil {
+		return models.GameRecord{}, err
+	}
+	switch g.Result {
+	case FirstWins:
+		record.Winner = record.Player1
+	case SecondWins:
+		record.Winner = record.Player2
+	}
+	return record, nil
+}
+
+// Rebuild replays the game and returns it positioned after the last move.
+func (g *Game) Rebuild() (*models.Game, error) {
+	record, err := g.Record()
+	if err != nil {
+		return nil, err
+	}
+
+	variant, _ := engine.LookupVariant(record.Settings.Variant)
+	position, err := variant.NewState(record.Settings.Size, record.Settings.WinLength)
+	if err != nil {
+		return nil, err
+	}
+	for _, move := range record.Moves {
+		if err := position.Apply(move.Move); err != nil {
+			return nil, err
+		}
+	}
+
+	game := &models.Game{
+		ID:        record.ID,
+		Player1:   models.Player{NickName: record.Player1, Symbol: record.Symbol1, Settings: record.Settings},
+		Player2:   models.Player{NickName: record.Player2, Symbol: record.Symbol2, Settings: record.Settings},
+		Variant:   variant,
+		Settings:  record.Settings,
+		Position:  position,
+		Moves:     record.Moves,
+		OnGoing:   g.Result == Unknown && !position.Outcome().Over(),
+		Rated:     record.Settings.Rated,
+		EndReason: record.Reason,
+		Started:   record.Started,
+	}
+	game.CurrentPlayer, game.WaitingPlayer = &game.Player1, &game.Player2
+	if position.Turn() == engine.Second {
+		game.CurrentPlayer, game.WaitingPlayer = &game.Player2, &game.Player1
+	}
+	switch g.Result {
+	case FirstWins:
+		game.Winner, game.Loser = &game.Player1, &game.Player2
+	case SecondWins:
+		game.Winner, game.Loser = &game.Player2, &game.Player1
+	}
+	return game, nil
+}
+
+func (g *Game) settings() (models.GameSettings, error) {
+	name := g.Tag(TagVariant)
+	if name == "" {
+		name = engine.ClassicName
+	}
+	variant, ok := engine.LookupVariant(strings.ToLower(name))
+	if !ok {
+		return models.GameSettings{}, fmt.Errorf("unknown variant %q", name)
+	}
+
+	settings := models.GameSettings{Variant: variant.Name()}
+	size, winLength, fixed := variant.Board()
+	settings.Size, settings.WinLength = size, winLength
+	if !fixed {
+		var err error
+		if value := g.Tag(TagSize); value != "" {
+			if settings.Size, err = strconv.Atoi(value); err != nil {
+				return models.GameSettings{}, fmt.Errorf("invalid board size %q", value)
+			}
+		}
+		if value := g.Tag(TagWinLength); value != "" {
+			if settings.WinLength, err = strconv.Atoi(value); err != nil {
+				return models.GameSettings{}, fmt.Errorf("invalid win length %q", value)
+			}
+		}
+	}
+
+	tc, err := parseTimeControl(g.Tag(TagTimeControl))
+	if err != nil {
+		return models.GameSettings{}, err
+	}
+	settings.TimeControl = tc
+
+	switch strings.ToLower(g.Tag(TagRated)) {
+	case "", "no", "false":
+	case "yes", "true":
+		settings.Rated = true
+	default:
+		return models.GameSettings{}, fmt.Errorf("invalid rated tag %q", g.Tag(TagRated))
+	}
+	return settings, nil
+}
+
+// checkResult makes sure a game that ended over the board ended the way
+// its final position says it did.
+func checkResult(result string, reason models.EndReason, outcome engine.Outcome) error {
+	if result == Unknown || reason != models.EndNormal {
+		return nil
+	}
+	if !outcome.Over() {
+		return fmt.Errorf("the final position is not decided, but the result is %s", result)
+	}
+
+	expected := Draw
+	if winner, ok := outcome.Winner(); ok {
+		expected = FirstWins
+		if winner == engine.Second {
+			expected = SecondWins
+		}
+	}
+	if result != expected {
+		return fmt.Errorf("the final position is %s, but the result is %s", expected, result)
+	}
+	return nil
+}
+
+func validResult(s string) bool {
+	return s == FirstWins || s == SecondWins || s == Draw || s == Unknown
+}
+
+func formatTimeControl(tc models.TimeControl) string {
+	if !tc.Enabled() {
+		return "-"
+	}
+	return fmt.Sprintf("%d+%d", int(tc.Base.Seconds()), int(tc.Increment.Seconds()))
+}
+
+func parseTimeControl(value string) (models.TimeControl, error) {
+	if value == "" || value == "-" {
+		return models.TimeControl{}, nil
+	}
+	base, increment, found := strings.Cut(value, "+")
+	seconds, err := strconv.Atoi(base)
+	if err != nil || seconds < 0 {
+		return models.TimeControl{}, fmt.Errorf("invalid time control %q", value)
+	}
+	tc := models.TimeControl{Base: time.Duration(seconds) * time.Second}
+	if found {
+		seconds, err := strconv.Atoi(increment)
+		if err != nil || seconds < 0 {
+			return models.TimeControl{}, fmt.Errorf("invalid time control %q", value)
+		}
+		tc.Increment = time.Duration(seconds) * time.Second
+	}
+	return tc, nil
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(s)
+}
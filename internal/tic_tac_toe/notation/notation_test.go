@@ -0,0 +1,292 @@
+package notation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"tic_tac_toe/internal/tic_tac_toe/models"
+	"time"
+)
+
+// newRecord plays the moves and stores them the way a finished game is
+// stored.
+func newRecord(t *testing.T, settings models.GameSettings, winner string, reason models.EndReason, moves ...string) (models.GameRecord, engine.State) {
+	t.Helper()
+	variant, ok := engine.LookupVariant(settings.Variant)
+	if !ok {
+		t.Fatalf("variant %q is not registered", settings.Variant)
+	}
+	position, err := variant.NewState(settings.Size, settings.WinLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := models.GameRecord{
+		ID:       "0f8fad5b-d9cb-469f-a165-70867728950e",
+		Player1:  "alice",
+		Player2:  "bob",
+		Symbol1:  variant.SideName(engine.First),
+		Symbol2:  variant.SideName(engine.Second),
+		Settings: settings,
+		Started:  time.Date(2026, 3, 14, 15, 9, 26, 0, time.UTC),
+		Winner:   winner,
+		Reason:   reason,
+	}
+	for _, input := range moves {
+		side := position.Turn()
+		move, err := position.ParseMove(input)
+		if err == nil {
+			err = position.Apply(move)
+		}
+		if err != nil {
+			t.Fatalf("move %s: %v", input, err)
+		}
+		record.Moves = append(record.Moves, models.MoveRecord{Move: move, Side: side, Notation: position.FormatMove(move)})
+	}
+	record.MoveCount = len(record.Moves)
+	return record, position
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings models.GameSettings
+		winner   string
+		reason   models.EndReason
+		moves    []string
+	}{
+		{
+			name:     "classic",
+			settings: models.GameSettings{Variant: engine.ClassicName, Size: 3, WinLength: 3},
+			winner:   "alice",
+			reason:   models.EndNormal,
+			moves:    []string{"A1", "B1", "A2", "B2", "A3"},
+		},
+		{
+			name:     "classic draw",
+			settings: models.GameSettings{Variant: engine.ClassicName, Size: 3, WinLength: 3, Rated: true},
+			reason:   models.EndNormal,
+			moves:    []string{"A1", "B2", "C3", "A2", "C2", "C1", "A3", "B3", "B1"},
+		},
+		{
+			name: "large board on time",
+			settings: models.GameSettings{
+				Variant:     engine.ClassicName,
+				Size:        15,
+				WinLength:   5,
+				TimeControl: models.TimeControl{Base: 3 * time.Minute, Increment: 2 * time.Second},
+				Rated:       true,
+			},
+			winner: "bob",
+			reason: models.EndTimeout,
+			moves:  []string{"H8", "H9", "J10", "O15", "A1"},
+		},
+		{
+			name:     "misere",
+			settings: models.GameSettings{Variant: engine.MisereName, Size: 3, WinLength: 3},
+			winner:   "bob",
+			reason:   models.EndNormal,
+			moves:    []string{"A1", "B1", "A2", "B2", "A3"},
+		},
+		{
+			name:     "wild",
+			settings: models.GameSettings{Variant: engine.WildName, Size: 3, WinLength: 3},
+			winner:   "alice",
+			reason:   models.EndNormal,
+			moves:    []string{"A1O", "A2O", "A3O"},
+		},
+		{
+			name:     "notakto",
+			settings: models.GameSettings{Variant: engine.NotaktoName, Size: 3, WinLength: 3},
+			winner:   "bob",
+			reason:   models.EndNormal,
+			moves:    []string{"A1", "A2", "A3"},
+		},
+		{
+			name:     "order and chaos",
+			settings: models.GameSettings{Variant: engine.OrderChaosName, Size: 6, WinLength: 5},
+			winner:   "alice",
+			reason:   models.EndNormal,
+			moves:    []string{"A1X", "F1O", "A2X", "F2X", "A3X", "F4O", "A4X", "F6X", "A5X"},
+		},
+		{
+			name:     "ultimate resignation",
+			settings: models.GameSettings{Variant: engine.UltimateName, Size: 9, WinLength: 3},
+			winner:   "alice",
+			reason:   models.EndResignation,
+			moves:    []string{"5B2", "5A1", "1B1", "4A1", "1B2", "5C3", "9A1", "1C1", "7A1", "1C2", "8A1", "1A1", "1B3", "6A1"},
+		},
+		{
+			name:     "agreed draw",
+			settings: models.GameSettings{Variant: engine.ClassicName, Size: 3, WinLength: 3},
+			reason:   models.EndAgreement,
+			moves:    []string{"B2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, position := newRecord(t, tt.settings, tt.winner, tt.reason, tt.moves...)
+
+			text := FromRecord(want).String()
+			parsed, err := Parse(text)
+			if err != nil {
+				t.Fatalf("Parse: %v\n%s", err, text)
+			}
+			if again := parsed.String(); again != text {
+				t.Errorf("exporting the parsed game changed it:\n%s\nwant:\n%s", again, text)
+			}
+
+			got, err := parsed.Record()
+			if err != nil {
+				t.Fatalf("Record: %v\n%s", err, text)
+			}
+			if !got.Started.Equal(want.Started) {
+				t.Errorf("Started = %v, want %v", got.Started, want.Started)
+			}
+			got.Started = want.Started
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Record() = %+v\nwant %+v", got, want)
+			}
+
+			game, err := parsed.Rebuild()
+			if err != nil {
+				t.Fatalf("Rebuild: %v", err)
+			}
+			if game.Position.Turn() != position.Turn() || game.Position.Outcome() != position.Outcome() {
+				t.Errorf("rebuilt position has turn %v and outcome %v, want %v and %v", game.Position.Turn(), game.Position.Outcome(), position.Turn(), position.Outcome())
+			}
+			if !reflect.DeepEqual(game.Position.LegalMoves(), position.LegalMoves()) {
+				t.Error("the rebuilt position allows different moves")
+			}
+			if game.OnGoing {
+				t.Error("a finished game was rebuilt as ongoing")
+			}
+			switch {
+			case tt.winner == "" && game.Winner != nil:
+				t.Errorf("Winner = %s, want none", game.Winner.NickName)
+			case tt.winner != "" && (game.Winner == nil || game.Winner.NickName != tt.winner):
+				t.Errorf("Winner = %v, want %s", game.Winner, tt.winner)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	record, _ := newRecord(t, models.GameSettings{Variant: engine.ClassicName, Size: 3, WinLength: 3}, "alice", models.EndNormal, "A1", "B1", "A2", "B2", "A3")
+	record.Player1 = `al "the pal" ice`
+	record.Winner = record.Player1
+	text := FromRecord(record).String()
+
+	for _, want := range []string{
+		`[First "al \"the pal\" ice"]`,
+		`[Variant "classic"]`,
+		`[TimeControl "-"]`,
+		`[Result "1-0"]`,
+		"\n\n1. A1 B1 2. A2 B2 3. A3 1-0\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("export is missing %q:\n%s", want, text)
+		}
+	}
+
+	parsed, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Tag(TagFirst); got != record.Player1 {
+		t.Errorf("First = %q, want %q", got, record.Player1)
+	}
+}
+
+func TestStringWrapsLongGames(t *testing.T) {
+	g := &Game{Result: Unknown}
+	for i := 0; i < 120; i++ {
+		g.Moves = append(g.Moves, "H10")
+	}
+	for _, line := range strings.Split(strings.TrimSpace(g.String()), "\n") {
+		if len(line) > lineWidth {
+			t.Errorf("line is %d characters long: %q", len(line), line)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		moves  []string
+		result string
+	}{
+		{name: "moves only", text: "1. B2 A1 2. C3", moves: []string{"B2", "A1", "C3"}, result: Unknown},
+		{name: "comments", text: "1. B2 {centre} A1 {corner}\n2. C3 *", moves: []string{"B2", "A1", "C3"}, result: Unknown},
+		{name: "numbers joined to moves", text: "1.B2 A1 2.C3", moves: []string{"B2", "A1", "C3"}, result: Unknown},
+		{name: "result from the tag", text: "[Result \"1/2-1/2\"]\n\n1. B2", moves: []string{"B2"}, result: Draw},
+		{name: "empty tag value", text: "[Event \"\"]\n\n1. B2 1-0", moves: []string{"B2"}, result: FirstWins},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(g.Moves, tt.moves) {
+				t.Errorf("Moves = %q, want %q", g.Moves, tt.moves)
+			}
+			if g.Result != tt.result {
+				t.Errorf("Result = %q, want %q", g.Result, tt.result)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "malformed tag", text: "[Variant classic]\n\n1. B2"},
+		{name: "duplicate tag", text: "[Variant \"classic\"]\n[Variant \"wild\"]\n\n1. B2"},
+		{name: "duplicate empty tag", text: "[Variant \"\"]\n[Variant \"wild\"]\n\n1. B2"},
+		{name: "move number out of sequence", text: "1. B2 A1 3. C3"},
+		{name: "moves after the result", text: "1. B2 1-0 A1"},
+		{name: "unterminated comment", text: "1. B2 {centre"},
+		{name: "invalid result tag", text: "[Result \"2-0\"]\n\n1. B2"},
+		{name: "result does not match the tag", text: "[Result \"1-0\"]\n\n1. B2 0-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.text); err == nil {
+				t.Errorf("Parse(%q) succeeded", tt.text)
+			}
+		})
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "unknown variant", text: "[Variant \"chess\"]\n\n1. B2 *"},
+		{name: "unknown termination", text: "[Termination \"boredom\"]\n\n1. B2 *"},
+		{name: "illegal move", text: "1. B2 B2 *"},
+		{name: "undecided position with a result", text: "1. B2 1-0"},
+		{name: "wrong winner", text: "1. A1 B1 2. A2 B2 3. A3 0-1"},
+		{name: "invalid size", text: "[Size \"big\"]\n\n1. B2 *"},
+		{name: "invalid time control", text: "[TimeControl \"5m\"]\n\n1. B2 *"},
+		{name: "invalid rated tag", text: "[Rated \"maybe\"]\n\n1. B2 *"},
+		{name: "invalid date", text: "[Date \"yesterday\"]\n\n1. B2 *"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(tt.text)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if _, err := g.Record(); err == nil {
+				t.Errorf("Record() accepted %q", tt.text)
+			}
+		})
+	}
+}
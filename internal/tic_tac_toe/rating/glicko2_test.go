@@ -0,0 +1,76 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+// TestUpdatePaperExample checks the worked example from Glickman's
+// Glicko-2 paper.
+func TestUpdatePaperExample(t *testing.T) {
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	results := []Result{
+		{Opponent: Rating{Rating: 1400, Deviation: 30, Volatility: DefaultVolatility}, Score: 1},
+		{Opponent: Rating{Rating: 1550, Deviation: 100, Volatility: DefaultVolatility}, Score: 0},
+		{Opponent: Rating{Rating: 1700, Deviation: 300, Volatility: DefaultVolatility}, Score: 0},
+	}
+
+	got := Update(player, results)
+	if !near(got.Rating, 1464.06, 0.01) {
+		t.Errorf("Rating = %.4f, want 1464.06", got.Rating)
+	}
+	if !near(got.Deviation, 151.52, 0.01) {
+		t.Errorf("Deviation = %.4f, want 151.52", got.Deviation)
+	}
+	if !near(got.Volatility, 0.05999, 0.00001) {
+		t.Errorf("Volatility = %.6f, want 0.05999", got.Volatility)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name  string
+		score float64
+		check func(before, after Rating) bool
+	}{
+		{name: "win raises the rating", score: 1, check: func(before, after Rating) bool { return after.Rating > before.Rating }},
+		{name: "loss lowers the rating", score: 0, check: func(before, after Rating) bool { return after.Rating < before.Rating }},
+		{name: "draw between equals keeps the rating", score: 0.5, check: func(before, after Rating) bool { return near(after.Rating, before.Rating, 1e-9) }},
+		{name: "a game narrows the deviation", score: 1, check: func(before, after Rating) bool { return after.Deviation < before.Deviation }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := Default()
+			after := Update(before, []Result{{Opponent: Default(), Score: tt.score}})
+			if !tt.check(before, after) {
+				t.Errorf("Update(%+v, score %.1f) = %+v", before, tt.score, after)
+			}
+		})
+	}
+}
+
+func TestUpdateWithoutGames(t *testing.T) {
+	player := Rating{Rating: 1700, Deviation: 50, Volatility: 0.06}
+	got := Update(player, nil)
+	if got.Rating != player.Rating || got.Volatility != player.Volatility {
+		t.Errorf("a period without games changed the rating to %+v", got)
+	}
+	if want := math.Sqrt(50*50 + math.Pow(0.06*scale, 2)); !near(got.Deviation, want, 1e-9) {
+		t.Errorf("Deviation = %.4f, want %.4f", got.Deviation, want)
+	}
+}
+
+func TestExpected(t *testing.T) {
+	if got := Expected(Default(), Default()); !near(got, 0.5, 1e-9) {
+		t.Errorf("Expected between equals = %.4f, want 0.5", got)
+	}
+	strong := Rating{Rating: 1900, Deviation: 50, Volatility: DefaultVolatility}
+	weak := Rating{Rating: 1300, Deviation: 50, Volatility: DefaultVolatility}
+	if got, against := Expected(strong, weak), Expected(weak, strong); got <= 0.9 || !near(got+against, 1, 0.01) {
+		t.Errorf("Expected(strong, weak) = %.4f, Expected(weak, strong) = %.4f", got, against)
+	}
+}
@@ -0,0 +1,201 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"time"
+)
+
+const (
+	defaultMaxNodes = 2_000_000
+	defaultTimeout  = 3 * time.Second
+)
+
+var (
+	ErrUnsupported = errors.New("the solver only supports single-board games")
+	ErrTooComplex  = errors.New("position is too complex to solve")
+)
+
+type Value int
+
+const (
+	Loss Value = iota - 1
+	Draw
+	Win
+)
+
+func (v Value) String() string {
+	switch v {
+	case Win:
+		return "win"
+	case Loss:
+		return "loss"
+	}
+	return "draw"
+}
+
+// Evaluation is the game-theoretic value of a position for the side to
+// move, and how many plies it takes to reach the end with best play.
+type Evaluation struct {
+	Value Value
+	Plies int
+}
+
+func (e Evaluation) String() string {
+	if e.Value == Draw {
+		return "draw"
+	}
+	return fmt.Sprintf("%s in %d", e.Value, e.Plies)
+}
+
+// better reports whether e is preferable to other for the side to move:
+// quicker wins, slower losses.
+func (e Evaluation) better(other Evaluation) bool {
+	if e.Value != other.Value {
+		return e.Value > other.Value
+	}
+	switch e.Value {
+	case Win:
+		return e.Plies < other.Plies
+	case Loss:
+		return e.Plies > other.Plies
+	}
+	return false
+}
+
+// parent turns the value of a child position into the value of the move
+// leading to it, seen from the side that made that move.
+func (e Evaluation) parent() Evaluation {
+	return Evaluation{Value: -e.Value, Plies: e.Plies + 1}
+}
+
+type MoveEvaluation struct {
+	Move       engine.Move
+	Evaluation Evaluation
+}
+
+type Analysis struct {
+	Evaluation Evaluation
+	BestMoves  []engine.Move
+	Moves      []MoveEvaluation
+}
+
+// Solver runs an exhaustive negamax search. Within one call, solved
+// positions are kept in a transposition table keyed on the canonical form
+// of the board under the eight symmetries of the square, so mirrored and
+// rotated positions are only ever solved once. Every call gets its own
+// table, so a Solver is safe for concurrent use and calls never wait for
+// each other.
+type Solver struct {
+	MaxNodes int
+	Timeout  time.Duration
+}
+
+func New() *Solver {
+	return &Solver{
+		MaxNodes: defaultMaxNodes,
+		Timeout:  defaultTimeout,
+	}
+}
+
+// search is the state of a single Solve or Analyze call.
+type search struct {
+	maxNodes int
+	table    map[string]Evaluation
+	nodes    int
+	deadline time.Time
+}
+
+func (s *Solver) start() *search {
+	srch := &search{maxNodes: s.MaxNodes, table: make(map[string]Evaluation)}
+	if s.Timeout > 0 {
+		srch.deadline = time.Now().Add(s.Timeout)
+	}
+	return srch
+}
+
+func (s *Solver) Solve(state engine.State) (Evaluation, error) {
+	pos, ok := state.(*engine.Position)
+	if !ok {
+		return Evaluation{}, ErrUnsupported
+	}
+
+	return s.start().solve(pos.Clone().(*engine.Position))
+}
+
+func (s *Solver) Analyze(state engine.State) (Analysis, error) {
+	pos, ok := state.(*engine.Position)
+	if !ok {
+		return Analysis{}, ErrUnsupported
+	}
+	if pos.Outcome().Over() {
+		return Analysis{}, engine.ErrGameOver
+	}
+
+	srch := s.start()
+	var analysis Analysis
+	for i, m := range pos.LegalMoves() {
+		child := pos.Clone().(*engine.Position)
+		child.Apply(m)
+		eval, err := srch.solve(child)
+		if err != nil {
+			return Analysis{}, err
+		}
+		eval = eval.parent()
+		analysis.Moves = append(analysis.Moves, MoveEvaluation{Move: m, Evaluation: eval})
+
+		if i == 0 || eval.better(analysis.Evaluation) {
+			analysis.Evaluation = eval
+			analysis.BestMoves = []engine.Move{m}
+		} else if eval == analysis.Evaluation {
+			analysis.BestMoves = append(analysis.BestMoves, m)
+		}
+	}
+	return analysis, nil
+}
+
+func (s *search) solve(pos *engine.Position) (Evaluation, error) {
+	if outcome := pos.Outcome(); outcome.Over() {
+		if winner, ok := outcome.Winner(); ok {
+			if winner == pos.Turn() {
+				return Evaluation{Value: Win}, nil
+			}
+			return Evaluation{Value: Loss}, nil
+		}
+		return Evaluation{Value: Draw}, nil
+	}
+
+	key := canonicalKey(pos)
+	if eval, ok := s.table[key]; ok {
+		return eval, nil
+	}
+
+	s.nodes++
+	if s.maxNodes > 0 && s.nodes > s.maxNodes {
+		return Evaluation{}, ErrTooComplex
+	}
+	if !s.deadline.IsZero() && s.nodes%1024 == 0 && time.Now().After(s.deadline) {
+		return Evaluation{}, ErrTooComplex
+	}
+
+	var best Evaluation
+	for i, m := range pos.LegalMoves() {
+		child := pos.Clone().(*engine.Position)
+		child.Apply(m)
+		eval, err := s.solve(child)
+		if err != nil {
+			return Evaluation{}, err
+		}
+		eval = eval.parent()
+		if i == 0 || eval.better(best) {
+			best = eval
+		}
+		if best.Value == Win && best.Plies == 1 {
+			break
+		}
+	}
+
+	s.table[key] = best
+	return best, nil
+}
@@ -0,0 +1,98 @@
+package solver
+
+import (
+	"errors"
+	"testing"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+	"time"
+)
+
+func position(t *testing.T, variant engine.GridVariant, size, winLength int, moves ...string) *engine.Position {
+	t.Helper()
+	pos, err := engine.NewVariantPosition(variant, size, winLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range moves {
+		m, err := pos.ParseMove(input)
+		if err == nil {
+			err = pos.Apply(m)
+		}
+		if err != nil {
+			t.Fatalf("move %s: %v", input, err)
+		}
+	}
+	return pos
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		variant engine.GridVariant
+		moves   []string
+		want    Evaluation
+	}{
+		{name: "classic is a draw", variant: engine.Classic, want: Evaluation{Value: Draw}},
+		{name: "misere is a draw", variant: engine.Misere, want: Evaluation{Value: Draw}},
+		{name: "win in one", variant: engine.Classic, moves: []string{"A1", "B1", "A2", "B2"}, want: Evaluation{Value: Win, Plies: 1}},
+		{name: "fork wins in three", variant: engine.Classic, moves: []string{"A1", "B2", "C3", "A3"}, want: Evaluation{Value: Win, Plies: 3}},
+		{name: "lost despite the block", variant: engine.Classic, moves: []string{"A1", "B1", "A2"}, want: Evaluation{Value: Loss, Plies: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New().Solve(position(t, tt.variant, 3, 3, tt.moves...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Value != tt.want.Value || (got.Value != Draw && got.Plies != tt.want.Plies) {
+				t.Errorf("Solve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	analysis, err := New().Analyze(position(t, engine.Classic, 3, 3, "A1", "B1", "A2", "B2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Evaluation != (Evaluation{Value: Win, Plies: 1}) {
+		t.Errorf("Evaluation = %v, want win in 1", analysis.Evaluation)
+	}
+	if len(analysis.BestMoves) != 1 || analysis.BestMoves[0] != (engine.Move{Row: 0, Col: 2}) {
+		t.Errorf("BestMoves = %v, want [A3]", analysis.BestMoves)
+	}
+	if len(analysis.Moves) != 5 {
+		t.Errorf("analyzed %d moves, want 5", len(analysis.Moves))
+	}
+}
+
+func TestUnsupportedAndTooComplex(t *testing.T) {
+	if _, err := New().Solve(engine.NewUltimatePosition()); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Solve(ultimate) = %v, want %v", err, ErrUnsupported)
+	}
+
+	s := &Solver{MaxNodes: 1000}
+	if _, err := s.Analyze(position(t, engine.Classic, 15, 5)); !errors.Is(err, ErrTooComplex) {
+		t.Errorf("Analyze(15x15) = %v, want %v", err, ErrTooComplex)
+	}
+}
+
+func TestConcurrentCallsDoNotWait(t *testing.T) {
+	s := &Solver{Timeout: 2 * time.Second}
+	done := make(chan struct{})
+	go func() {
+		s.Analyze(position(t, engine.Classic, 15, 5))
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := s.Solve(position(t, engine.Classic, 3, 3, "A1", "B1", "A2", "B2")); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("a small solve waited %s for a large one", elapsed)
+	}
+	<-done
+}
@@ -0,0 +1,64 @@
+package solver
+
+import (
+	"strings"
+	"sync"
+	"tic_tac_toe/internal/tic_tac_toe/engine"
+)
+
+var symmetries sync.Map
+
+// symmetriesFor returns, for each of the eight rotations and reflections of
+// a size x size board, the cell each cell is moved to.
+func symmetriesFor(size int) [8][]int {
+	if perms, ok := symmetries.Load(size); ok {
+		return perms.([8][]int)
+	}
+
+	var perms [8][]int
+	transforms := [8]func(r, c int) (int, int){
+		func(r, c int) (int, int) { return r, c },
+		func(r, c int) (int, int) { return c, size - 1 - r },
+		func(r, c int) (int, int) { return size - 1 - r, size - 1 - c },
+		func(r, c int) (int, int) { return size - 1 - c, r },
+		func(r, c int) (int, int) { return r, size - 1 - c },
+		func(r, c int) (int, int) { return size - 1 - r, c },
+		func(r, c int) (int, int) { return c, r },
+		func(r, c int) (int, int) { return size - 1 - c, size - 1 - r },
+	}
+	for i, transform := range transforms {
+		perms[i] = make([]int, size*size)
+		for r := 0; r < size; r++ {
+			for c := 0; c < size; c++ {
+				tr, tc := transform(r, c)
+				perms[i][r*size+c] = tr*size + tc
+			}
+		}
+	}
+
+	actual, _ := symmetries.LoadOrStore(size, perms)
+	return actual.([8][]int)
+}
+
+// canonicalKey identifies a position up to symmetry: the variant and rules,
+// the side to move, and the smallest of the eight transformed boards.
+func canonicalKey(pos *engine.Position) string {
+	var best []byte
+	board := make([]byte, len(pos.Cells))
+	for _, perm := range symmetriesFor(pos.Size) {
+		for from, to := range perm {
+			board[to] = pos.Cells[from][0]
+		}
+		if best == nil || string(board) < string(best) {
+			best = append(best[:0], board...)
+		}
+	}
+
+	var key strings.Builder
+	key.WriteString(pos.Variant().Name())
+	key.WriteByte(byte('0' + pos.Size))
+	key.WriteByte(byte('0' + pos.WinLength))
+	key.WriteByte(byte('0' + pos.Turn()))
+	key.Write(best)
+	return key.String()
+}
@@ -0,0 +1,676 @@
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Format string
+
+const (
+	RoundRobin        Format = "roundrobin"
+	Swiss             Format = "swiss"
+	SingleElimination Format = "single"
+	DoubleElimination Format = "double"
+)
+
+var Formats = []Format{RoundRobin, Swiss, SingleElimination, DoubleElimination}
+
+var ErrTooFewPlayers = errors.New("a tournament needs at least two players")
+
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(name)
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q", name)
+}
+
+func (f Format) Elimination() bool {
+	return f == SingleElimination || f == DoubleElimination
+}
+
+func (f Format) Description() string {
+	switch f {
+	case RoundRobin:
+		return "round-robin"
+	case Swiss:
+		return "Swiss"
+	case SingleElimination:
+		return "single elimination"
+	case DoubleElimination:
+		return "double elimination"
+	}
+	return string(f)
+}
+
+type Result int
+
+const (
+	Pending Result = iota
+	FirstWins
+	SecondWins
+	Draw
+	// BothLose scores a game neither player showed up for as a loss for
+	// both of them.
+	BothLose
+)
+
+const (
+	WinnersBracket = "winners"
+	LosersBracket  = "losers"
+	GrandFinal     = "final"
+)
+
+// Pairing is one game of a round. A pairing without a second player is a
+// bye, which counts as a win.
+type Pairing struct {
+	Round   int
+	First   string
+	Second  string
+	Bracket string
+	Result  Result
+	Forfeit bool
+}
+
+func (p *Pairing) Bye() bool {
+	return p.Second == ""
+}
+
+func (p *Pairing) Has(player string) bool {
+	return p.First == player || (!p.Bye() && p.Second == player)
+}
+
+func (p *Pairing) Opponent(player string) string {
+	if p.First == player {
+		return p.Second
+	}
+	return p.First
+}
+
+// Score returns the points player earned from the pairing.
+func (p *Pairing) Score(player string) float64 {
+	switch {
+	case p.Bye():
+		return 1
+	case p.Result == Draw:
+		return 0.5
+	case p.Result == FirstWins && p.First == player, p.Result == SecondWins && p.Second == player:
+		return 1
+	}
+	return 0
+}
+
+// Tournament pairs rounds for a fixed list of players, given in seed order.
+type Tournament struct {
+	Format  Format
+	Players []string
+	Rounds  [][]*Pairing
+
+	schedule [][]*Pairing
+	total    int
+}
+
+func New(format Format, players []string) (*Tournament, error) {
+	if len(players) < 2 {
+		return nil, ErrTooFewPlayers
+	}
+	t := &Tournament{Format: format, Players: players}
+
+	switch format {
+	case RoundRobin:
+		t.schedule = roundRobinSchedule(players)
+		t.total = len(t.schedule)
+	case Swiss:
+		for 1<<t.total < len(players) {
+			t.total++
+		}
+	case SingleElimination, DoubleElimination:
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return t, nil
+}
+
+// TotalRounds returns how many rounds the tournament has, or 0 when that
+// depends on the results.
+func (t *Tournament) TotalRounds() int {
+	return t.total
+}
+
+func (t *Tournament) Current() []*Pairing {
+	if len(t.Rounds) == 0 {
+		return nil
+	}
+	return t.Rounds[len(t.Rounds)-1]
+}
+
+func (t *Tournament) RoundComplete() bool {
+	for _, p := range t.Current() {
+		if !p.Bye() && p.Result == Pending {
+			return false
+		}
+	}
+	return true
+}
+
+// NextRound pairs the next round once the current one is complete. It
+// returns nil when the tournament is over.
+func (t *Tournament) NextRound() []*Pairing {
+	if !t.RoundComplete() || t.Finished() {
+		return nil
+	}
+
+	var round []*Pairing
+	switch t.Format {
+	case RoundRobin:
+		round = t.schedule[len(t.Rounds)]
+	case Swiss:
+		round = t.swissRound()
+	case SingleElimination:
+		round = t.singleEliminationRound()
+	case DoubleElimination:
+		round = t.doubleEliminationRound()
+	}
+
+	for _, p := range round {
+		p.Round = len(t.Rounds) + 1
+	}
+	t.Rounds = append(t.Rounds, round)
+	return round
+}
+
+func (t *Tournament) Finished() bool {
+	if !t.RoundComplete() {
+		return false
+	}
+	switch t.Format {
+	case RoundRobin, Swiss:
+		return len(t.Rounds) == t.total
+	case SingleElimination:
+		return len(t.Rounds) > 0 && len(t.advancing()) <= 1
+	case DoubleElimination:
+		return len(t.Rounds) > 0 && len(t.remaining()) <= 1
+	}
+	return true
+}
+
+// Winner returns the tournament winner once it is finished.
+// An elimination tournament whose last players all lost has no winner.
+func (t *Tournament) Winner() (string, bool) {
+	if !t.Finished() {
+		return "", false
+	}
+	first := t.Standings()[0]
+	if first.Eliminated {
+		return "", false
+	}
+	return first.Player, true
+}
+
+// Advances returns the player who goes through from a pairing, or "" when
+// both players lost. In the elimination formats a draw goes to the higher
+// seed.
+func (t *Tournament) Advances(p *Pairing) string {
+	switch {
+	case p.Bye(), p.Result == FirstWins:
+		return p.First
+	case p.Result == SecondWins:
+		return p.Second
+	case p.Result == BothLose:
+		return ""
+	}
+	if t.seed(p.Second) < t.seed(p.First) {
+		return p.Second
+	}
+	return p.First
+}
+
+func (t *Tournament) seed(player string) int {
+	for i, p := range t.Players {
+		if p == player {
+			return i
+		}
+	}
+	return len(t.Players)
+}
+
+// roundRobinSchedule uses the circle method: one player stays put while the
+// others rotate, so everyone meets everyone once.
+func roundRobinSchedule(players []string) [][]*Pairing {
+	circle := append([]string(nil), players...)
+	if len(circle)%2 == 1 {
+		circle = append(circle, "")
+	}
+	n := len(circle)
+
+	var rounds [][]*Pairing
+	for r := 0; r < n-1; r++ {
+		var round []*Pairing
+		for i := 0; i < n/2; i++ {
+			first, second := circle[i], circle[n-1-i]
+			if (r+i)%2 == 1 {
+				first, second = second, first
+			}
+			if first == "" {
+				first, second = second, first
+			}
+			round = append(round, &Pairing{First: first, Second: second})
+		}
+		rounds = append(rounds, round)
+
+		last := circle[n-1]
+		copy(circle[2:], circle[1:n-1])
+		circle[1] = last
+	}
+	return rounds
+}
+
+func (t *Tournament) played(a, b string) bool {
+	for _, round := range t.Rounds {
+		for _, p := range round {
+			if p.Has(a) && p.Has(b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t *Tournament) hadBye(player string) bool {
+	for _, round := range t.Rounds {
+		for _, p := range round {
+			if p.Bye() && p.First == player {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// swissRound pairs players with equal or similar scores who have not met
+// yet. With an odd number of players the lowest ranked player without a
+// bye sits out.
+func (t *Tournament) swissRound() []*Pairing {
+	var order []string
+	for _, standing := range t.Standings() {
+		order = append(order, standing.Player)
+	}
+
+	var round []*Pairing
+	if len(order)%2 == 1 {
+		bye := len(order) - 1
+		for i := len(order) - 1; i >= 0; i-- {
+			if !t.hadBye(order[i]) {
+				bye = i
+				break
+			}
+		}
+		round = append(round, &Pairing{First: order[bye]})
+		order = append(order[:bye], order[bye+1:]...)
+	}
+
+	var games []*Pairing
+	if pairs := t.pairWithoutRematches(order); pairs != nil {
+		for _, pair := range pairs {
+			games = append(games, t.balanceColours(pair[0], pair[1]))
+		}
+		return append(games, round...)
+	}
+
+	// Nobody can avoid a rematch, so pair greedily instead.
+	paired := make(map[string]bool)
+	for i, player := range order {
+		if paired[player] {
+			continue
+		}
+		opponent := ""
+		for _, candidate := range order[i+1:] {
+			if paired[candidate] {
+				continue
+			}
+			if opponent == "" {
+				opponent = candidate
+			}
+			if !t.played(player, candidate) {
+				opponent = candidate
+				break
+			}
+		}
+		paired[player], paired[opponent] = true, true
+		games = append(games, t.balanceColours(player, opponent))
+	}
+	return append(games, round...)
+}
+
+// pairWithoutRematches pairs the players in order, each with the highest
+// placed opponent they have not met yet, backtracking when that would leave
+// someone without a new opponent. It returns nil if there is no such
+// pairing.
+func (t *Tournament) pairWithoutRematches(order []string) [][2]string {
+	if len(order) == 0 {
+		return [][2]string{}
+	}
+	player := order[0]
+	for i := 1; i < len(order); i++ {
+		if t.played(player, order[i]) {
+			continue
+		}
+		rest := append(append([]string(nil), order[1:i]...), order[i+1:]...)
+		if pairs := t.pairWithoutRematches(rest); pairs != nil {
+			return append([][2]string{{player, order[i]}}, pairs...)
+		}
+	}
+	return nil
+}
+
+// balanceColours gives the first move to whoever has had it less often.
+func (t *Tournament) balanceColours(a, b string) *Pairing {
+	if t.firstMoves(b) < t.firstMoves(a) {
+		a, b = b, a
+	}
+	return &Pairing{First: a, Second: b}
+}
+
+func (t *Tournament) firstMoves(player string) int {
+	count := 0
+	for _, round := range t.Rounds {
+		for _, p := range round {
+			if !p.Bye() && p.First == player {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// bracketOrder returns the seeds of a bracket of the given size in the
+// order they are paired, so that the top seeds can only meet late.
+func bracketOrder(size int) []int {
+	order := []int{0}
+	for len(order) < size {
+		next := make([]int, 0, 2*len(order))
+		for _, seed := range order {
+			next = append(next, seed, 2*len(order)-1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// advancing returns the players still in a single elimination bracket, in
+// bracket order.
+func (t *Tournament) advancing() []string {
+	if len(t.Rounds) == 0 {
+		return t.Players
+	}
+	var players []string
+	for _, p := range t.Current() {
+		if player := t.Advances(p); player != "" {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+// eliminated returns the players knocked out of a single elimination
+// bracket. Players whose game is still pending are not out yet.
+func (t *Tournament) eliminated() map[string]bool {
+	eliminated := make(map[string]bool)
+	for _, round := range t.Rounds {
+		for _, p := range round {
+			switch {
+			case p.Bye(), p.Result == Pending:
+				continue
+			case p.Result == BothLose:
+				eliminated[p.First], eliminated[p.Second] = true, true
+				continue
+			}
+			eliminated[p.Opponent(t.Advances(p))] = true
+		}
+	}
+	return eliminated
+}
+
+func (t *Tournament) singleEliminationRound() []*Pairing {
+	var players []string
+	if len(t.Rounds) == 0 {
+		size := 1
+		for size < len(t.Players) {
+			size *= 2
+		}
+		for _, seed := range bracketOrder(size) {
+			player := ""
+			if seed < len(t.Players) {
+				player = t.Players[seed]
+			}
+			players = append(players, player)
+		}
+	} else {
+		players = t.advancing()
+		if len(players)%2 == 1 {
+			players = append(players, "")
+		}
+	}
+
+	var round []*Pairing
+	for i := 0; i+1 < len(players); i += 2 {
+		first, second := players[i], players[i+1]
+		if first == "" {
+			first, second = second, first
+		}
+		round = append(round, &Pairing{First: first, Second: second})
+	}
+	return round
+}
+
+// losses counts the games each player has lost, for double elimination.
+func (t *Tournament) losses() map[string]int {
+	losses := make(map[string]int)
+	for _, round := range t.Rounds {
+		for _, p := range round {
+			switch {
+			case p.Bye(), p.Result == Pending:
+				continue
+			case p.Result == BothLose:
+				losses[p.First]++
+				losses[p.Second]++
+				continue
+			}
+			losses[p.Opponent(t.Advances(p))]++
+		}
+	}
+	return losses
+}
+
+// remaining returns the players with fewer than two losses, in seed order.
+func (t *Tournament) remaining() []string {
+	losses := t.losses()
+	var players []string
+	for _, player := range t.Players {
+		if losses[player] < 2 {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+// doubleEliminationRound pairs the unbeaten players among themselves and
+// the players with one loss among themselves. When one of each is left
+// they meet in the grand final, which is replayed if the unbeaten player
+// loses it.
+func (t *Tournament) doubleEliminationRound() []*Pairing {
+	losses := t.losses()
+	var winners, losers []string
+	for _, player := range t.remaining() {
+		if losses[player] == 0 {
+			winners = append(winners, player)
+		} else {
+			losers = append(losers, player)
+		}
+	}
+
+	if len(winners)+len(losers) == 2 && len(winners) < 2 {
+		players := append(winners, losers...)
+		return []*Pairing{{First: players[0], Second: players[1], Bracket: GrandFinal}}
+	}
+
+	var round []*Pairing
+	for _, bracket := range []struct {
+		name    string
+		players []string
+	}{{WinnersBracket, winners}, {LosersBracket, losers}} {
+		if len(bracket.players) == 0 {
+			continue
+		}
+		round = append(round, t.pairBracket(bracket.name, bracket.players)...)
+	}
+	return round
+}
+
+// pairBracket pairs players top seed against bottom seed, avoiding
+// rematches where it can. An odd player out gets a bye.
+func (t *Tournament) pairBracket(bracket string, players []string) []*Pairing {
+	players = append([]string(nil), players...)
+
+	var bye *Pairing
+	if len(players)%2 == 1 {
+		sitOut := 0
+		for i, player := range players {
+			if !t.hadBye(player) {
+				sitOut = i
+				break
+			}
+		}
+		bye = &Pairing{First: players[sitOut], Bracket: bracket}
+		players = append(players[:sitOut], players[sitOut+1:]...)
+	}
+
+	var round []*Pairing
+	for len(players) > 0 {
+		first := players[0]
+		opponent := len(players) - 1
+		for j := len(players) - 1; j > 0; j-- {
+			if !t.played(first, players[j]) {
+				opponent = j
+				break
+			}
+		}
+		pairing := t.balanceColours(first, players[opponent])
+		pairing.Bracket = bracket
+		round = append(round, pairing)
+		players = append(players[1:opponent], players[opponent+1:]...)
+	}
+	if bye != nil {
+		round = append(round, bye)
+	}
+	return round
+}
+
+type Standing struct {
+	Player     string
+	Points     float64
+	Wins       int
+	Draws      int
+	Losses     int
+	Tiebreak   float64
+	Eliminated bool
+}
+
+// Standings ranks the players: by points and then Buchholz (the sum of the
+// opponents' points) in round-robin and Swiss, and by how far they got in
+// the elimination formats.
+func (t *Tournament) Standings() []Standing {
+	standings := make(map[string]*Standing)
+	for _, player := range t.Players {
+		standings[player] = &Standing{Player: player}
+	}
+
+	for _, round := range t.Rounds {
+		for _, p := range round {
+			if !p.Bye() && p.Result == Pending {
+				continue
+			}
+			for _, player := range []string{p.First, p.Second} {
+				if player == "" {
+					continue
+				}
+				standing := standings[player]
+				score := p.Score(player)
+				standing.Points += score
+				switch {
+				case score == 1:
+					standing.Wins++
+				case score == 0.5:
+					standing.Draws++
+				default:
+					standing.Losses++
+				}
+			}
+		}
+	}
+
+	if !t.Format.Elimination() {
+		for _, round := range t.Rounds {
+			for _, p := range round {
+				if p.Bye() || p.Result == Pending {
+					continue
+				}
+				standings[p.First].Tiebreak += standings[p.Second].Points
+				standings[p.Second].Tiebreak += standings[p.First].Points
+			}
+		}
+	}
+
+	switch t.Format {
+	case SingleElimination:
+		eliminated := t.eliminated()
+		for _, standing := range standings {
+			standing.Eliminated = eliminated[standing.Player]
+			standing.Tiebreak = float64(t.roundsSurvived(standing.Player))
+		}
+	case DoubleElimination:
+		losses := t.losses()
+		for _, standing := range standings {
+			standing.Eliminated = losses[standing.Player] >= 2
+			standing.Tiebreak = float64(t.roundsSurvived(standing.Player))
+		}
+	}
+
+	ranked := make([]Standing, 0, len(standings))
+	for _, player := range t.Players {
+		ranked = append(ranked, *standings[player])
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Eliminated != b.Eliminated {
+			return !a.Eliminated
+		}
+		if t.Format.Elimination() {
+			return a.Tiebreak > b.Tiebreak
+		}
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		return a.Tiebreak > b.Tiebreak
+	})
+	return ranked
+}
+
+// roundsSurvived counts the rounds in which the player had a pairing.
+func (t *Tournament) roundsSurvived(player string) int {
+	count := 0
+	for _, round := range t.Rounds {
+		for _, p := range round {
+			if p.Has(player) {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
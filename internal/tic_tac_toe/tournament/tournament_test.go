@@ -0,0 +1,446 @@
+package tournament
+
+import (
+	"fmt"
+	"testing"
+)
+
+func players(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("p%d", i+1)
+	}
+	return names
+}
+
+func newTournament(t *testing.T, format Format, n int) *Tournament {
+	t.Helper()
+	tour, err := New(format, players(n))
+	if err != nil {
+		t.Fatalf("New(%s, %d players): %v", format, n, err)
+	}
+	return tour
+}
+
+// higherSeedWins decides every game for the better seeded player.
+func higherSeedWins(tour *Tournament, p *Pairing) Result {
+	if tour.seed(p.First) < tour.seed(p.Second) {
+		return FirstWins
+	}
+	return SecondWins
+}
+
+func win(p *Pairing, winner string) Result {
+	if p.First == winner {
+		return FirstWins
+	}
+	return SecondWins
+}
+
+// run plays the tournament to the end and returns its rounds.
+func run(t *testing.T, tour *Tournament, decide func(tour *Tournament, p *Pairing) Result) [][]*Pairing {
+	t.Helper()
+	for i := 0; ; i++ {
+		if i > 50 {
+			t.Fatal("the tournament did not finish")
+		}
+		round := tour.NextRound()
+		if round == nil {
+			break
+		}
+		for _, p := range round {
+			if !p.Bye() {
+				p.Result = decide(tour, p)
+			}
+		}
+	}
+	if !tour.Finished() {
+		t.Fatal("NextRound returned nil before the tournament finished")
+	}
+	return tour.Rounds
+}
+
+// checkRounds verifies that every player appears exactly once per round
+// and that the round numbers are set.
+func checkRounds(t *testing.T, tour *Tournament, rounds [][]*Pairing, everyone bool) {
+	t.Helper()
+	for i, round := range rounds {
+		seen := make(map[string]bool)
+		for _, p := range round {
+			if p.Round != i+1 {
+				t.Errorf("round %d has a pairing numbered %d", i+1, p.Round)
+			}
+			for _, player := range []string{p.First, p.Second} {
+				if player == "" {
+					continue
+				}
+				if seen[player] {
+					t.Errorf("%s is paired twice in round %d", player, i+1)
+				}
+				seen[player] = true
+			}
+		}
+		if everyone && len(seen) != len(tour.Players) {
+			t.Errorf("round %d pairs %d of %d players", i+1, len(seen), len(tour.Players))
+		}
+	}
+}
+
+func countByes(rounds [][]*Pairing) map[string]int {
+	byes := make(map[string]int)
+	for _, round := range rounds {
+		for _, p := range round {
+			if p.Bye() {
+				byes[p.First]++
+			}
+		}
+	}
+	return byes
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(RoundRobin, players(1)); err != ErrTooFewPlayers {
+		t.Errorf("New with one player = %v, want %v", err, ErrTooFewPlayers)
+	}
+	if _, err := New("knockout", players(4)); err == nil {
+		t.Error("New accepted an unknown format")
+	}
+	for _, format := range Formats {
+		parsed, err := ParseFormat(string(format))
+		if err != nil || parsed != format {
+			t.Errorf("ParseFormat(%q) = %q, %v", format, parsed, err)
+		}
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	tests := []struct {
+		players int
+		rounds  int
+		byes    int
+	}{
+		{players: 2, rounds: 1},
+		{players: 4, rounds: 3},
+		{players: 5, rounds: 5, byes: 1},
+		{players: 6, rounds: 5},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d players", tt.players), func(t *testing.T) {
+			tour := newTournament(t, RoundRobin, tt.players)
+			if got := tour.TotalRounds(); got != tt.rounds {
+				t.Fatalf("TotalRounds() = %d, want %d", got, tt.rounds)
+			}
+			rounds := run(t, tour, higherSeedWins)
+			if len(rounds) != tt.rounds {
+				t.Fatalf("played %d rounds, want %d", len(rounds), tt.rounds)
+			}
+			checkRounds(t, tour, rounds, true)
+
+			for i, a := range tour.Players {
+				for _, b := range tour.Players[i+1:] {
+					meetings := 0
+					for _, round := range rounds {
+						for _, p := range round {
+							if p.Has(a) && p.Has(b) {
+								meetings++
+							}
+						}
+					}
+					if meetings != 1 {
+						t.Errorf("%s and %s met %d times", a, b, meetings)
+					}
+				}
+			}
+			for _, player := range tour.Players {
+				if got := countByes(rounds)[player]; got != tt.byes {
+					t.Errorf("%s had %d byes, want %d", player, got, tt.byes)
+				}
+			}
+
+			if winner, ok := tour.Winner(); !ok || winner != "p1" {
+				t.Errorf("Winner() = %q, %v, want p1", winner, ok)
+			}
+			standings := tour.Standings()
+			if top := standings[0]; top.Points != float64(tt.players-1+tt.byes) || top.Losses != 0 {
+				t.Errorf("winner's standing = %+v", top)
+			}
+			if last := standings[len(standings)-1]; last.Player != fmt.Sprintf("p%d", tt.players) {
+				t.Errorf("last place = %s, want the lowest seed", last.Player)
+			}
+		})
+	}
+}
+
+func TestSwiss(t *testing.T) {
+	tests := []struct {
+		players int
+		rounds  int
+	}{
+		{players: 2, rounds: 1},
+		{players: 5, rounds: 3},
+		{players: 8, rounds: 3},
+		{players: 9, rounds: 4},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d players", tt.players), func(t *testing.T) {
+			tour := newTournament(t, Swiss, tt.players)
+			if got := tour.TotalRounds(); got != tt.rounds {
+				t.Fatalf("TotalRounds() = %d, want %d", got, tt.rounds)
+			}
+			rounds := run(t, tour, higherSeedWins)
+			if len(rounds) != tt.rounds {
+				t.Fatalf("played %d rounds, want %d", len(rounds), tt.rounds)
+			}
+			checkRounds(t, tour, rounds, true)
+
+			for i, round := range rounds {
+				byes := 0
+				for _, p := range round {
+					if p.Bye() {
+						byes++
+					}
+				}
+				if want := tt.players % 2; byes != want {
+					t.Errorf("round %d has %d byes, want %d", i+1, byes, want)
+				}
+			}
+			for player, byes := range countByes(rounds) {
+				if byes > 1 {
+					t.Errorf("%s had %d byes", player, byes)
+				}
+			}
+			for i, a := range tour.Players {
+				for _, b := range tour.Players[i+1:] {
+					meetings := 0
+					for _, round := range rounds {
+						for _, p := range round {
+							if p.Has(a) && p.Has(b) {
+								meetings++
+							}
+						}
+					}
+					if meetings > 1 {
+						t.Errorf("%s and %s met %d times", a, b, meetings)
+					}
+				}
+			}
+
+			if winner, ok := tour.Winner(); !ok || winner != "p1" {
+				t.Errorf("Winner() = %q, %v, want p1", winner, ok)
+			}
+		})
+	}
+}
+
+func TestSwissPairsEqualScores(t *testing.T) {
+	tour := newTournament(t, Swiss, 8)
+	run(t, tour, higherSeedWins)
+
+	// Round two pairs the round one winners among themselves, and the
+	// losers among themselves.
+	won := make(map[string]bool)
+	for _, p := range tour.Rounds[0] {
+		won[tour.Advances(p)] = true
+	}
+	for _, p := range tour.Rounds[1] {
+		if won[p.First] != won[p.Second] {
+			t.Errorf("round 2 pairs %s with %s, who had different scores", p.First, p.Second)
+		}
+	}
+}
+
+func TestSingleElimination(t *testing.T) {
+	tests := []struct {
+		players int
+		rounds  []int
+		byes    int
+	}{
+		{players: 2, rounds: []int{1}},
+		{players: 4, rounds: []int{2, 1}},
+		{players: 5, rounds: []int{4, 2, 1}, byes: 3},
+		{players: 8, rounds: []int{4, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d players", tt.players), func(t *testing.T) {
+			tour := newTournament(t, SingleElimination, tt.players)
+			rounds := run(t, tour, higherSeedWins)
+			if len(rounds) != len(tt.rounds) {
+				t.Fatalf("played %d rounds, want %d", len(rounds), len(tt.rounds))
+			}
+			checkRounds(t, tour, rounds, false)
+			for i, round := range rounds {
+				if len(round) != tt.rounds[i] {
+					t.Errorf("round %d has %d pairings, want %d", i+1, len(round), tt.rounds[i])
+				}
+			}
+			if got := len(countByes(rounds[:1])); got != tt.byes {
+				t.Errorf("round 1 has %d byes, want %d", got, tt.byes)
+			}
+
+			if winner, ok := tour.Winner(); !ok || winner != "p1" {
+				t.Errorf("Winner() = %q, %v, want p1", winner, ok)
+			}
+			standings := tour.Standings()
+			for _, standing := range standings[1:] {
+				if !standing.Eliminated {
+					t.Errorf("%s is not eliminated after the final", standing.Player)
+				}
+			}
+		})
+	}
+}
+
+func TestSingleEliminationSeeding(t *testing.T) {
+	tour := newTournament(t, SingleElimination, 8)
+	round := tour.NextRound()
+	want := [][2]string{{"p1", "p8"}, {"p4", "p5"}, {"p2", "p7"}, {"p3", "p6"}}
+	for i, p := range round {
+		if p.First != want[i][0] || p.Second != want[i][1] {
+			t.Errorf("pairing %d = %s vs %s, want %s vs %s", i+1, p.First, p.Second, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestSingleEliminationStandingsWhilePlaying(t *testing.T) {
+	tour := newTournament(t, SingleElimination, 4)
+	round := tour.NextRound()
+	for _, standing := range tour.Standings() {
+		if standing.Eliminated {
+			t.Errorf("%s is out before playing", standing.Player)
+		}
+	}
+
+	round[0].Result = SecondWins
+	for _, standing := range tour.Standings() {
+		if out := standing.Player == round[0].First; standing.Eliminated != out {
+			t.Errorf("%s eliminated = %v, want %v", standing.Player, standing.Eliminated, out)
+		}
+	}
+	if tour.NextRound() != nil {
+		t.Error("the next round was paired before the current one finished")
+	}
+}
+
+func TestEliminationDrawAdvancesHigherSeed(t *testing.T) {
+	tour := newTournament(t, SingleElimination, 2)
+	run(t, tour, func(*Tournament, *Pairing) Result { return Draw })
+	if winner, ok := tour.Winner(); !ok || winner != "p1" {
+		t.Errorf("Winner() = %q, %v, want p1", winner, ok)
+	}
+}
+
+func TestDoubleNoShow(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		decide func(tour *Tournament, p *Pairing) Result
+		winner string
+	}{
+		{
+			name:   "single elimination semi-final",
+			format: SingleElimination,
+			decide: func(tour *Tournament, p *Pairing) Result {
+				if p.Has("p2") && p.Has("p3") {
+					return BothLose
+				}
+				return higherSeedWins(tour, p)
+			},
+			winner: "p1",
+		},
+		{
+			name:   "single elimination final",
+			format: SingleElimination,
+			decide: func(tour *Tournament, p *Pairing) Result {
+				if p.Round == 2 {
+					return BothLose
+				}
+				return higherSeedWins(tour, p)
+			},
+		},
+		{
+			name:   "round-robin",
+			format: RoundRobin,
+			decide: func(tour *Tournament, p *Pairing) Result {
+				if p.Has("p1") && p.Has("p2") {
+					return BothLose
+				}
+				return higherSeedWins(tour, p)
+			},
+			winner: "p1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tour := newTournament(t, tt.format, 4)
+			run(t, tour, tt.decide)
+			winner, ok := tour.Winner()
+			if ok != (tt.winner != "") || winner != tt.winner {
+				t.Errorf("Winner() = %q, %v, want %q", winner, ok, tt.winner)
+			}
+			for _, standing := range tour.Standings() {
+				if standing.Draws != 0 {
+					t.Errorf("%s scored a draw for a game nobody played", standing.Player)
+				}
+			}
+		})
+	}
+}
+
+func TestDoubleElimination(t *testing.T) {
+	tests := []struct {
+		players int
+	}{
+		{players: 2},
+		{players: 4},
+		{players: 5},
+		{players: 8},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d players", tt.players), func(t *testing.T) {
+			tour := newTournament(t, DoubleElimination, tt.players)
+			rounds := run(t, tour, higherSeedWins)
+			checkRounds(t, tour, rounds, false)
+
+			final := rounds[len(rounds)-1]
+			if len(final) != 1 || final[0].Bracket != GrandFinal {
+				t.Errorf("the last round is not a grand final: %+v", final)
+			}
+			if winner, ok := tour.Winner(); !ok || winner != "p1" {
+				t.Errorf("Winner() = %q, %v, want p1", winner, ok)
+			}
+
+			losses := tour.losses()
+			if losses["p1"] != 0 {
+				t.Errorf("the winner lost %d games", losses["p1"])
+			}
+			for _, player := range tour.Players[1:] {
+				if losses[player] != 2 {
+					t.Errorf("%s finished with %d losses, want 2", player, losses[player])
+				}
+			}
+		})
+	}
+}
+
+func TestDoubleEliminationGrandFinalReset(t *testing.T) {
+	tour := newTournament(t, DoubleElimination, 2)
+	winners := []string{"p1", "p2", "p2"}
+	rounds := run(t, tour, func(tour *Tournament, p *Pairing) Result {
+		return win(p, winners[p.Round-1])
+	})
+
+	if len(rounds) != 3 {
+		t.Fatalf("played %d rounds, want 3", len(rounds))
+	}
+	if rounds[0][0].Bracket != WinnersBracket {
+		t.Errorf("round 1 is in the %s bracket", rounds[0][0].Bracket)
+	}
+	for _, round := range rounds[1:] {
+		if round[0].Bracket != GrandFinal {
+			t.Errorf("round %d is in the %s bracket, want the grand final", round[0].Round, round[0].Bracket)
+		}
+	}
+	if winner, ok := tour.Winner(); !ok || winner != "p2" {
+		t.Errorf("Winner() = %q, %v, want p2", winner, ok)
+	}
+}